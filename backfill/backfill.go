@@ -0,0 +1,75 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/commoddity/bank-informer/csv"
+	"github.com/commoddity/bank-informer/persistence"
+	"github.com/commoddity/bank-informer/pokt"
+)
+
+const dateFormat = "2006-01-02"
+
+// POKTBalances populates persistence and the CSV file with the POKT wallet balance
+// at the end of each day from the from date to the to date, inclusive. Days that
+// already have a POKT value in persistence or the CSV file are skipped.
+//
+// Historical prices are not known here, so backfilled days have a fiat value of 0
+// until they are filled in by a price backfill.
+func POKTBalances(ctx context.Context, poktClient *pokt.Client, p *persistence.Persistence, from, to time.Time) error {
+	if to.Before(from) {
+		return fmt.Errorf("backfill end date %s is before start date %s", to.Format(dateFormat), from.Format(dateFormat))
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateFormat)
+		key := fmt.Sprintf("POKT-%s", date)
+
+		if _, err := p.GetAverageCryptoValues(key); err == nil {
+			fmt.Printf("⏭️  Skipping %s, POKT values already stored\n", date)
+			continue
+		}
+		hasRecord, err := csv.HasRecord(date, "POKT")
+		if err != nil {
+			return err
+		}
+		if hasRecord {
+			fmt.Printf("⏭️  Skipping %s, POKT values already in CSV\n", date)
+			continue
+		}
+
+		// Use the balance at the last block of the day
+		endOfDay := time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, time.Local)
+		height, err := poktClient.GetHeightAtTime(ctx, endOfDay)
+		if err != nil {
+			return fmt.Errorf("failed to get height for %s: %w", date, err)
+		}
+
+		balance, err := poktClient.GetWalletBalanceAtHeight(ctx, height)
+		if err != nil {
+			return fmt.Errorf("failed to get POKT balance at height %d: %w", height, err)
+		}
+
+		err = p.WriteSnapshot(persistence.Snapshot{
+			RunID:        persistence.NewRunID(),
+			Time:         endOfDay,
+			Assets:       map[string]persistence.CryptoValues{"POKT": {CryptoBalance: balance, Backfilled: true}},
+			BlockHeights: map[string]int64{"pokt": height},
+			Backfilled:   true,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = csv.WriteCryptoValuesToCSVForDate(p, []string{"POKT"}, date)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Backfilled %s POKT balance at height %d\n", date, height)
+	}
+
+	return nil
+}
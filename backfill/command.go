@@ -0,0 +1,84 @@
+package backfill
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/commoddity/bank-informer/config"
+	"github.com/commoddity/bank-informer/persistence"
+	"github.com/commoddity/bank-informer/pokt"
+	"github.com/commoddity/bank-informer/price"
+)
+
+// Commands that backfill past days instead of fetching the current balances
+const (
+	CommandBalances = "backfill"
+	CommandPrices   = "backfill-prices"
+)
+
+// IsCommand reports whether name is a backfill command.
+func IsCommand(name string) bool {
+	return name == CommandBalances || name == CommandPrices
+}
+
+// Run parses the -from and -to flags of the backfill command in args and runs it.
+// The backfill command backfills the POKT wallet balance for each day in the date
+// range, and the backfill-prices command fills in the missing prices.
+func Run(ctx context.Context, command string, args []string, cfg *config.Config, p *persistence.Persistence, httpClient *http.Client) error {
+	from, to, err := parseDateRange(command, args)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case CommandBalances:
+		poktClient := pokt.NewClient(pokt.Config{
+			PathApiUrl:        cfg.PathApiUrl,
+			PathApiKey:        cfg.PathApiKey,
+			POKTWalletAddress: cfg.PoktWalletAddress,
+			HttpClient:        httpClient,
+		}, make(chan string), &sync.Mutex{}, &sync.WaitGroup{})
+
+		return POKTBalances(ctx, poktClient, p, from, to)
+	case CommandPrices:
+		// Use the first configured price provider
+		provider, ok := price.NewProvider(cfg.PriceProviders[0], cfg, p, httpClient).(price.HistoricalProvider)
+		if !ok {
+			return fmt.Errorf("price provider %s does not support historical prices", cfg.PriceProviders[0])
+		}
+
+		return Prices(ctx, provider, p, cfg.CryptoValues, cfg.CryptoFiatConversion, from, to)
+	default:
+		return fmt.Errorf("unknown backfill command: %s", command)
+	}
+}
+
+// parseDateRange parses the -from and -to flags of a backfill command.
+func parseDateRange(command string, args []string) (time.Time, time.Time, error) {
+	backfillFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	yesterday := time.Now().AddDate(0, 0, -1).Format(dateFormat)
+	fromFlag := backfillFlags.String("from", "", "first date to backfill (YYYY-MM-DD)")
+	toFlag := backfillFlags.String("to", yesterday, "last date to backfill (YYYY-MM-DD)")
+	if err := backfillFlags.Parse(args); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if *fromFlag == "" {
+		backfillFlags.Usage()
+		return time.Time{}, time.Time{}, fmt.Errorf("missing required flag: -from")
+	}
+	from, err := time.ParseInLocation(dateFormat, *fromFlag, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from date: %w", err)
+	}
+	to, err := time.ParseInLocation(dateFormat, *toFlag, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to date: %w", err)
+	}
+
+	return from, to, nil
+}
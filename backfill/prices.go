@@ -0,0 +1,144 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/commoddity/bank-informer/csv"
+	"github.com/commoddity/bank-informer/persistence"
+	"github.com/commoddity/bank-informer/price"
+)
+
+// gap is a crypto with no price data on a day, and the balance to value it at.
+type gap struct {
+	crypto  string
+	balance float64
+}
+
+// Prices finds the days from the from date to the to date, inclusive, on which a crypto
+// has no price data in persistence or the CSV file, and fills them in with the daily
+// close from the historical price provider. The rows are marked as backfilled.
+//
+// The balance of a gap is the balance stored for that day if there is one, such as one
+// from a POKT balance backfill, or else the last balance in the CSV file before that day.
+func Prices(ctx context.Context, provider price.HistoricalProvider, p *persistence.Persistence, cryptos []string, fiat string, from, to time.Time) error {
+	if to.Before(from) {
+		return fmt.Errorf("backfill end date %s is before start date %s", to.Format(dateFormat), from.Format(dateFormat))
+	}
+
+	records, err := csv.ReadRecords()
+	if err != nil {
+		return err
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateFormat)
+
+		gaps := findGaps(p, records, cryptos, date)
+		if len(gaps) == 0 {
+			continue
+		}
+
+		symbols := make([]string, len(gaps))
+		for i, gap := range gaps {
+			symbols[i] = gap.crypto
+		}
+
+		prices, err := provider.GetHistoricalQuotes(ctx, symbols, fiat, day)
+		if err != nil {
+			return fmt.Errorf("failed to get historical prices for %s: %w", date, err)
+		}
+
+		var filled []string
+		for _, gap := range gaps {
+			closePrice, ok := prices[gap.crypto]
+			if !ok {
+				fmt.Printf("⚠️  No historical %s price found for %s\n", gap.crypto, date)
+				continue
+			}
+
+			key := fmt.Sprintf("%s-%s", gap.crypto, date)
+			err = p.ReplaceCryptoValues(key, persistence.CryptoValues{
+				CryptoBalance: gap.balance,
+				FiatValue:     closePrice,
+				FiatBalance:   gap.balance * closePrice,
+				Backfilled:    true,
+			})
+			if err != nil {
+				return err
+			}
+			filled = append(filled, gap.crypto)
+		}
+
+		if len(filled) == 0 {
+			continue
+		}
+
+		err = csv.WriteCryptoValuesToCSVForDate(p, filled, date)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Backfilled %s prices for %v\n", date, filled)
+	}
+
+	return nil
+}
+
+// findGaps returns the cryptos without a price on date that have a known balance.
+func findGaps(p *persistence.Persistence, records []csv.Record, cryptos []string, date string) []gap {
+	var gaps []gap
+
+	for _, crypto := range cryptos {
+		avgValues, err := p.GetAverageCryptoValues(fmt.Sprintf("%s-%s", crypto, date))
+		stored := err == nil
+		if stored && avgValues.FiatValue > 0 {
+			continue
+		}
+
+		record, hasRecord := findRecord(records, crypto, date)
+		if hasRecord && record.FiatValue > 0 {
+			continue
+		}
+
+		switch {
+		case stored:
+			gaps = append(gaps, gap{crypto: crypto, balance: avgValues.CryptoBalance})
+		case hasRecord:
+			gaps = append(gaps, gap{crypto: crypto, balance: record.CryptoBalance})
+		default:
+			if previous, ok := findPreviousRecord(records, crypto, date); ok {
+				gaps = append(gaps, gap{crypto: crypto, balance: previous.CryptoBalance})
+			}
+		}
+	}
+
+	return gaps
+}
+
+func findRecord(records []csv.Record, crypto, date string) (csv.Record, bool) {
+	for _, record := range records {
+		if record.Crypto == crypto && record.Date == date {
+			return record, true
+		}
+	}
+	return csv.Record{}, false
+}
+
+// findPreviousRecord returns the latest record of the crypto before date.
+func findPreviousRecord(records []csv.Record, crypto, date string) (csv.Record, bool) {
+	var previous csv.Record
+	var found bool
+	for _, record := range records {
+		// Dates are formatted as YYYY-MM-DD, so they sort lexically
+		if record.Crypto != crypto || record.Date >= date {
+			continue
+		}
+		if !found || record.Date > previous.Date {
+			previous = record
+			found = true
+		}
+	}
+	return previous, found
+}
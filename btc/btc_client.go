@@ -0,0 +1,156 @@
+package btc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const (
+	receiveChain = 0
+	changeChain  = 1
+)
+
+type Config struct {
+	EsploraApiUrl string
+	Addresses     []string
+	XPubs         []string
+	GapLimit      int
+	HttpClient    *http.Client
+}
+
+type Client struct {
+	Config       Config
+	baseUrl      string
+	httpClient   *http.Client
+	progressChan chan string
+	mutex        *sync.Mutex
+	waitGroup    *sync.WaitGroup
+}
+
+type txoStats struct {
+	FundedTxoSum int64 `json:"funded_txo_sum"`
+	SpentTxoSum  int64 `json:"spent_txo_sum"`
+	TxCount      int64 `json:"tx_count"`
+}
+
+type addressOutput struct {
+	Address      string   `json:"address"`
+	ChainStats   txoStats `json:"chain_stats"`
+	MempoolStats txoStats `json:"mempool_stats"`
+}
+
+func NewClient(config Config, progressChan chan string, mutex *sync.Mutex, waitGroup *sync.WaitGroup) *Client {
+	return &Client{
+		Config:       config,
+		baseUrl:      strings.TrimSuffix(config.EsploraApiUrl, "/"),
+		httpClient:   config.HttpClient,
+		progressChan: progressChan,
+		mutex:        mutex,
+		waitGroup:    waitGroup,
+	}
+}
+
+// GetWalletBalance sums the confirmed and unconfirmed balances of the configured
+// addresses and the addresses derived from the configured extended public keys.
+func (c *Client) GetWalletBalance(ctx context.Context, balances map[string]float64) error {
+	var totalSats int64
+
+	for _, address := range c.Config.Addresses {
+		stats, err := c.getAddressStats(ctx, address)
+		if err != nil {
+			return err
+		}
+		totalSats += stats.balance()
+	}
+
+	for _, xpub := range c.Config.XPubs {
+		sats, err := c.getExtendedKeyBalance(ctx, xpub)
+		if err != nil {
+			return err
+		}
+		totalSats += sats
+	}
+
+	c.progressChan <- "BTC"
+
+	// Modify the passed map with the balance
+	c.mutex.Lock()
+	balances["BTC"] += float64(totalSats) / 1e8
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// getExtendedKeyBalance derives the receive and change addresses of the extended
+// public key, stopping each chain after GapLimit consecutive unused addresses.
+func (c *Client) getExtendedKeyBalance(ctx context.Context, xpub string) (int64, error) {
+	key, err := parseExtendedKey(xpub)
+	if err != nil {
+		return 0, err
+	}
+
+	var totalSats int64
+	for _, chain := range []uint32{receiveChain, changeChain} {
+		chainKey, err := key.child(chain)
+		if err != nil {
+			return 0, err
+		}
+
+		unused := 0
+		for index := uint32(0); unused < c.Config.GapLimit; index++ {
+			addressKey, err := chainKey.child(index)
+			if errors.Is(err, errInvalidChild) {
+				continue
+			}
+			if err != nil {
+				return 0, err
+			}
+
+			address, err := addressKey.address()
+			if err != nil {
+				return 0, err
+			}
+
+			stats, err := c.getAddressStats(ctx, address)
+			if err != nil {
+				return 0, err
+			}
+
+			if stats.ChainStats.TxCount == 0 && stats.MempoolStats.TxCount == 0 {
+				unused++
+				continue
+			}
+			unused = 0
+			totalSats += stats.balance()
+		}
+	}
+
+	return totalSats, nil
+}
+
+func (c *Client) getAddressStats(ctx context.Context, address string) (addressOutput, error) {
+	url := fmt.Sprintf("%s/address/%s", c.baseUrl, address)
+
+	header := http.Header{
+		"Accept": []string{"application/json"},
+	}
+
+	resp, err := client.Get[addressOutput](ctx, url, header, c.httpClient)
+	if err != nil {
+		return resp, fmt.Errorf("failed to get BTC address %s: %w", address, err)
+	}
+	return resp, nil
+}
+
+// balance returns the confirmed and unconfirmed balance of the address in satoshis.
+func (a addressOutput) balance() int64 {
+	confirmed := a.ChainStats.FundedTxoSum - a.ChainStats.SpentTxoSum
+	unconfirmed := a.MempoolStats.FundedTxoSum - a.MempoolStats.SpentTxoSum
+	return confirmed + unconfirmed
+}
@@ -0,0 +1,358 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/ripemd160" //nolint:staticcheck // RIPEMD-160 is required for Bitcoin addresses
+)
+
+type addressType int
+
+const (
+	p2pkh      addressType = iota // xpub, legacy "1..." addresses
+	p2shP2wpkh                    // ypub, nested SegWit "3..." addresses
+	p2wpkh                        // zpub, native SegWit "bc1q..." addresses
+)
+
+// Mainnet extended public key version bytes
+var extendedKeyVersions = map[uint32]addressType{
+	0x0488b21e: p2pkh,
+	0x049d7cb2: p2shP2wpkh,
+	0x04b24746: p2wpkh,
+}
+
+// secp256k1 curve parameters
+var (
+	curveP, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	curveN, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	curveGx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	curveGy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+	curveB     = big.NewInt(7)
+)
+
+var errInvalidChild = errors.New("invalid child key, skip to the next index")
+
+// extendedKey is a BIP32 extended public key.
+type extendedKey struct {
+	addressType addressType
+	chainCode   []byte
+	publicKey   []byte // compressed, 33 bytes
+}
+
+// parseExtendedKey decodes a base58 xpub, ypub or zpub.
+func parseExtendedKey(key string) (*extendedKey, error) {
+	data, err := base58CheckDecode(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extended public key: %w", err)
+	}
+	if len(data) != 78 {
+		return nil, fmt.Errorf("invalid extended public key length: %d", len(data))
+	}
+
+	version := binary.BigEndian.Uint32(data[:4])
+	addressType, ok := extendedKeyVersions[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported extended public key version: %x", version)
+	}
+
+	publicKey := data[45:78]
+	if publicKey[0] != 0x02 && publicKey[0] != 0x03 {
+		return nil, fmt.Errorf("extended key is not a public key")
+	}
+
+	return &extendedKey{
+		addressType: addressType,
+		chainCode:   data[13:45],
+		publicKey:   publicKey,
+	}, nil
+}
+
+// child derives the non-hardened child public key at index.
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	data := make([]byte, 37)
+	copy(data, k.publicKey)
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(curveN) >= 0 {
+		return nil, errInvalidChild
+	}
+
+	parentX, parentY, err := decompressPoint(k.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ilX, ilY := scalarBaseMult(il)
+	childX, childY := addPoints(ilX, ilY, parentX, parentY)
+	if childX == nil {
+		return nil, errInvalidChild
+	}
+
+	return &extendedKey{
+		addressType: k.addressType,
+		chainCode:   sum[32:],
+		publicKey:   compressPoint(childX, childY),
+	}, nil
+}
+
+// address encodes the public key as an address of the extended key's address type.
+func (k *extendedKey) address() (string, error) {
+	pubKeyHash := hash160(k.publicKey)
+
+	switch k.addressType {
+	case p2pkh:
+		return base58CheckEncode(append([]byte{0x00}, pubKeyHash...)), nil
+	case p2shP2wpkh:
+		redeemScript := append([]byte{0x00, 0x14}, pubKeyHash...)
+		return base58CheckEncode(append([]byte{0x05}, hash160(redeemScript)...)), nil
+	case p2wpkh:
+		return segwitEncode("bc", 0, pubKeyHash)
+	default:
+		return "", fmt.Errorf("unsupported address type: %d", k.addressType)
+	}
+}
+
+/* ------------ secp256k1 Point Arithmetic ------------ */
+
+func decompressPoint(publicKey []byte) (*big.Int, *big.Int, error) {
+	x := new(big.Int).SetBytes(publicKey[1:])
+
+	// y² = x³ + 7 (mod p)
+	ySquared := new(big.Int).Exp(x, big.NewInt(3), curveP)
+	ySquared.Add(ySquared, curveB)
+	ySquared.Mod(ySquared, curveP)
+
+	// p ≡ 3 (mod 4), so the square root is y²^((p+1)/4)
+	exponent := new(big.Int).Add(curveP, big.NewInt(1))
+	exponent.Rsh(exponent, 2)
+	y := new(big.Int).Exp(ySquared, exponent, curveP)
+
+	if new(big.Int).Exp(y, big.NewInt(2), curveP).Cmp(ySquared) != 0 {
+		return nil, nil, fmt.Errorf("public key is not on the curve")
+	}
+	if y.Bit(0) != uint(publicKey[0]&1) {
+		y.Sub(curveP, y)
+	}
+	return x, y, nil
+}
+
+func compressPoint(x, y *big.Int) []byte {
+	publicKey := make([]byte, 33)
+	publicKey[0] = 0x02 | byte(y.Bit(0))
+	x.FillBytes(publicKey[1:])
+	return publicKey
+}
+
+// addPoints adds two affine points, where a nil x is the point at infinity.
+func addPoints(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+
+	var slope *big.Int
+	if x1.Cmp(x2) == 0 {
+		if new(big.Int).Add(y1, y2).Mod(new(big.Int).Add(y1, y2), curveP).Sign() == 0 {
+			return nil, nil
+		}
+		// slope = 3x² / 2y
+		numerator := new(big.Int).Mul(x1, x1)
+		numerator.Mul(numerator, big.NewInt(3))
+		denominator := new(big.Int).Lsh(y1, 1)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, curveP))
+	} else {
+		// slope = (y2 - y1) / (x2 - x1)
+		numerator := new(big.Int).Sub(y2, y1)
+		denominator := new(big.Int).Sub(x2, x1)
+		denominator.Mod(denominator, curveP)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, curveP))
+	}
+	slope.Mod(slope, curveP)
+
+	x3 := new(big.Int).Mul(slope, slope)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, curveP)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, slope)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, curveP)
+
+	return x3, y3
+}
+
+func scalarBaseMult(k *big.Int) (*big.Int, *big.Int) {
+	var x, y *big.Int
+	addX, addY := curveGx, curveGy
+	for i := 0; i < k.BitLen(); i++ {
+		if k.Bit(i) == 1 {
+			x, y = addPoints(x, y, addX, addY)
+		}
+		addX, addY = addPoints(addX, addY, addX, addY)
+	}
+	return x, y
+}
+
+/* ------------ Base58Check Encoding ------------ */
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58CheckEncode(payload []byte) string {
+	checksum := doubleSHA256(payload)
+	data := append(append([]byte{}, payload...), checksum[:4]...)
+
+	value := new(big.Int).SetBytes(data)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+
+	var encoded []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, base, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	// Leading zero bytes are encoded as leading "1"s
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+func base58CheckDecode(encoded string) ([]byte, error) {
+	value := new(big.Int)
+	base := big.NewInt(58)
+	leadingZeros := 0
+	for i, r := range encoded {
+		index := bytes.IndexRune([]byte(base58Alphabet), r)
+		if index < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+		if index == 0 && i == leadingZeros {
+			leadingZeros++
+		}
+		value.Mul(value, base)
+		value.Add(value, big.NewInt(int64(index)))
+	}
+
+	data := append(make([]byte, leadingZeros), value.Bytes()...)
+	if len(data) < 4 {
+		return nil, fmt.Errorf("base58 data too short")
+	}
+
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	expected := doubleSHA256(payload)
+	if !bytes.Equal(checksum, expected[:4]) {
+		return nil, fmt.Errorf("invalid base58 checksum")
+	}
+	return payload, nil
+}
+
+// hash160 returns RIPEMD160(SHA256(data)).
+func hash160(data []byte) []byte {
+	sha := sha256.Sum256(data)
+	hasher := ripemd160.New()
+	hasher.Write(sha[:])
+	return hasher.Sum(nil)
+}
+
+func doubleSHA256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+/* ------------ Bech32 Encoding ------------ */
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// segwitEncode encodes a witness program as a BIP173 bech32 address.
+func segwitEncode(hrp string, version byte, program []byte) (string, error) {
+	data, err := convertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	data = append([]byte{version}, data...)
+
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	encoded := []byte(hrp + "1")
+	for _, d := range data {
+		encoded = append(encoded, bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		encoded = append(encoded, bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return string(encoded), nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c>>5))
+	}
+	expanded = append(expanded, 0)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c&31))
+	}
+	return expanded
+}
+
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var converted []byte
+	accumulator := uint32(0)
+	bits := uint(0)
+	maxValue := uint32(1<<toBits) - 1
+
+	for _, value := range data {
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(accumulator>>bits&maxValue))
+		}
+	}
+
+	if pad && bits > 0 {
+		converted = append(converted, byte(accumulator<<(toBits-bits)&maxValue))
+	} else if !pad && (bits >= fromBits || accumulator<<(toBits-bits)&maxValue != 0) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return converted, nil
+}
@@ -0,0 +1,180 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// BIP32 test vector 1, the extended public keys of each parent and its non-hardened child.
+func TestChildMatchesBIP32TestVector1(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent string
+		index  uint32
+		child  string
+	}{
+		{
+			name:   "m/0H/1",
+			parent: "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			index:  1,
+			child:  "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		},
+		{
+			name:   "m/0H/1/2H/2/1000000000",
+			parent: "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+			index:  1000000000,
+			child:  "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent, err := parseExtendedKey(test.parent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := parseExtendedKey(test.child)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parent.child(test.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.publicKey, want.publicKey) {
+				t.Errorf("public key = %x, want %x", got.publicKey, want.publicKey)
+			}
+			if !bytes.Equal(got.chainCode, want.chainCode) {
+				t.Errorf("chain code = %x, want %x", got.chainCode, want.chainCode)
+			}
+		})
+	}
+}
+
+// The parent of m/0H/1/2H/2 is hardened, so it is built from the vector's public key and chain code.
+func TestChildMatchesBIP32TestVector1FromHardenedParent(t *testing.T) {
+	publicKey, _ := hex.DecodeString("0357bfe1e341d01c69fe5654309956cbea516822fba8a601743a012a7896ee8dc2")
+	chainCode, _ := hex.DecodeString("04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f")
+	parent := &extendedKey{addressType: p2pkh, chainCode: chainCode, publicKey: publicKey}
+
+	want, err := parseExtendedKey("xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parent.child(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.publicKey, want.publicKey) {
+		t.Errorf("public key = %x, want %x", got.publicKey, want.publicKey)
+	}
+	if !bytes.Equal(got.chainCode, want.chainCode) {
+		t.Errorf("chain code = %x, want %x", got.chainCode, want.chainCode)
+	}
+}
+
+func TestParseExtendedKeyDecodesBIP32TestVector1(t *testing.T) {
+	key, err := parseExtendedKey("xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPublicKey, _ := hex.DecodeString("0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2")
+	wantChainCode, _ := hex.DecodeString("873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508")
+	if !bytes.Equal(key.publicKey, wantPublicKey) {
+		t.Errorf("public key = %x, want %x", key.publicKey, wantPublicKey)
+	}
+	if !bytes.Equal(key.chainCode, wantChainCode) {
+		t.Errorf("chain code = %x, want %x", key.chainCode, wantChainCode)
+	}
+	if key.addressType != p2pkh {
+		t.Errorf("address type = %d, want p2pkh", key.addressType)
+	}
+}
+
+// The account keys and first addresses of the BIP49 and BIP84 test vectors, whose
+// mnemonic is "abandon abandon abandon abandon abandon abandon abandon abandon
+// abandon abandon abandon about".
+func TestAddressMatchesBIP49AndBIP84TestVectors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xpub    string
+		chain   uint32
+		address string
+	}{
+		{
+			name:    "BIP49 first receive address",
+			xpub:    "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP",
+			chain:   receiveChain,
+			address: "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf",
+		},
+		{
+			name:    "BIP49 first change address",
+			xpub:    "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP",
+			chain:   changeChain,
+			address: "34K56kSjgUCUSD8GTtuF7c9Zzwokbs6uZ7",
+		},
+		{
+			name:    "BIP84 first receive address",
+			xpub:    "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+			chain:   receiveChain,
+			address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+		},
+		{
+			name:    "BIP84 first change address",
+			xpub:    "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+			chain:   changeChain,
+			address: "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := parseExtendedKey(test.xpub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chainKey, err := key.child(test.chain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addressKey, err := chainKey.child(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			address, err := addressKey.address()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if address != test.address {
+				t.Errorf("address = %s, want %s", address, test.address)
+			}
+		})
+	}
+}
+
+func TestBase58CheckRoundTrip(t *testing.T) {
+	// A P2PKH payload with a leading zero version byte
+	payload, _ := hex.DecodeString("00751e76e8199196d454941c45d1b3a323f1433bd6")
+
+	encoded := base58CheckEncode(payload)
+	if encoded != "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH" {
+		t.Errorf("encoded = %s, want 1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH", encoded)
+	}
+
+	decoded, err := base58CheckDecode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, payload) {
+		t.Errorf("decoded = %x, want %x", decoded, payload)
+	}
+
+	if _, err := base58CheckDecode(encoded[:len(encoded)-1] + "J"); err == nil {
+		t.Error("expected an invalid checksum error")
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for requests to an upstream whose circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerPolicy configures the circuit breaker of an upstream. A request fails
+// if it fails with a network error, 5xx response or 429 response after all retries.
+type BreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failed requests, across
+	// runs, after which the breaker opens and requests fail without being sent.
+	FailureThreshold int
+	// CoolDown is the time after which an open breaker lets a single probe
+	// request through, which closes the breaker if it succeeds.
+	CoolDown time.Duration
+}
+
+var DefaultBreakerPolicy = BreakerPolicy{
+	FailureThreshold: 3,
+	CoolDown:         30 * time.Minute,
+}
+
+// BreakerState is the state of an upstream's circuit breaker. The breaker is
+// open if OpenedAt is set.
+type BreakerState struct {
+	Upstream string
+	Failures int
+	OpenedAt time.Time
+}
+
+// BreakerStore stores the state of each upstream's circuit breaker across runs.
+type BreakerStore interface {
+	GetBreakerStates() (map[string]BreakerState, error)
+	WriteBreakerState(state BreakerState) error
+}
+
+// probeKey is the context key that marks the probe request of an open breaker.
+type probeKey struct{}
+
+// isProbe reports whether the request is the probe request of an open breaker,
+// which is not retried so that a failing upstream is not kept waiting on.
+func isProbe(ctx context.Context) bool {
+	probe, _ := ctx.Value(probeKey{}).(bool)
+	return probe
+}
+
+type breakerTransport struct {
+	underlying http.RoundTripper
+	policy     BreakerPolicy
+	upstreams  map[string]BreakerPolicy
+	store      BreakerStore
+	debug      io.Writer
+
+	mutex   sync.Mutex
+	states  map[string]BreakerState
+	probing map[string]bool
+}
+
+func newBreakerTransport(underlying http.RoundTripper, policy BreakerPolicy, upstreams map[string]BreakerPolicy, store BreakerStore, debug io.Writer) (*breakerTransport, error) {
+	states, err := store.GetBreakerStates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get circuit breaker states: %w", err)
+	}
+
+	return &breakerTransport{
+		underlying: underlying,
+		policy:     policy,
+		upstreams:  upstreams,
+		store:      store,
+		debug:      debug,
+		states:     states,
+		probing:    make(map[string]bool),
+	}, nil
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	upstream, policy := t.policyFor(req)
+
+	probe, err := t.allow(upstream, policy)
+	if err != nil {
+		return nil, err
+	}
+	if probe {
+		req = req.WithContext(context.WithValue(req.Context(), probeKey{}, true))
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+
+	// Requests cancelled by the run are not failures of the upstream
+	if req.Context().Err() != nil {
+		t.release(upstream, probe)
+		return resp, err
+	}
+	t.report(upstream, policy, probe, !shouldRetry(resp, err))
+
+	return resp, err
+}
+
+// policyFor returns the name and breaker policy of the request's upstream, which
+// is its PATH service ID if it has one, or else its host.
+func (t *breakerTransport) policyFor(req *http.Request) (string, BreakerPolicy) {
+	host := req.URL.Hostname()
+	upstream := host
+	if serviceID := req.Header.Get(serviceIDHeader); serviceID != "" {
+		upstream = serviceID
+		if policy, ok := t.upstreams[serviceID]; ok {
+			return upstream, policy
+		}
+	}
+	if policy, ok := t.upstreams[host]; ok {
+		return upstream, policy
+	}
+	return upstream, t.policy
+}
+
+// allow returns an error if the upstream's breaker is open. Once the cool-down
+// has passed, it lets a single request through and reports it as the probe.
+func (t *breakerTransport) allow(upstream string, policy BreakerPolicy) (bool, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state := t.states[upstream]
+	if state.OpenedAt.IsZero() {
+		return false, nil
+	}
+
+	if until := state.OpenedAt.Add(policy.CoolDown); time.Now().Before(until) {
+		return false, fmt.Errorf("%w for %s until %s", ErrCircuitOpen, upstream, until.Format("2006-01-02 15:04:05"))
+	}
+	if t.probing[upstream] {
+		return false, fmt.Errorf("%w for %s while a probe request is in flight", ErrCircuitOpen, upstream)
+	}
+
+	t.probing[upstream] = true
+	return true, nil
+}
+
+// release ends the probe of the upstream's breaker without a result.
+func (t *breakerTransport) release(upstream string, probe bool) {
+	if probe {
+		t.mutex.Lock()
+		delete(t.probing, upstream)
+		t.mutex.Unlock()
+	}
+}
+
+// report records the result of a request to the upstream. Successful requests
+// close the breaker, and failed requests open it once the failure threshold is
+// reached or if the request was the probe.
+func (t *breakerTransport) report(upstream string, policy BreakerPolicy, probe, ok bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if probe {
+		delete(t.probing, upstream)
+	}
+
+	state := t.states[upstream]
+	if ok {
+		if state.Failures == 0 && state.OpenedAt.IsZero() {
+			return
+		}
+		if !state.OpenedAt.IsZero() && t.debug != nil {
+			fmt.Fprintf(t.debug, "🔌 Closed the circuit breaker for %s\n", upstream)
+		}
+		state = BreakerState{Upstream: upstream}
+	} else {
+		state.Upstream = upstream
+		state.Failures++
+		if probe || (state.OpenedAt.IsZero() && state.Failures >= policy.FailureThreshold) {
+			state.OpenedAt = time.Now()
+			if t.debug != nil {
+				fmt.Fprintf(t.debug, "🔌 Opened the circuit breaker for %s after %d failed request(s)\n", upstream, state.Failures)
+			}
+		}
+	}
+	t.states[upstream] = state
+
+	if err := t.store.WriteBreakerState(state); err != nil {
+		fmt.Printf("❌ Failed to store the circuit breaker state of %s: %s\n", upstream, err)
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBreakerStore keeps circuit breaker states in memory.
+type memoryBreakerStore struct {
+	mutex  sync.Mutex
+	states map[string]BreakerState
+}
+
+func (s *memoryBreakerStore) GetBreakerStates() (map[string]BreakerState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	states := make(map[string]BreakerState)
+	for upstream, state := range s.states {
+		states[upstream] = state
+	}
+	return states, nil
+}
+
+func (s *memoryBreakerStore) WriteBreakerState(state BreakerState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[state.Upstream] = state
+	return nil
+}
+
+// countingServer responds to every request with status and counts the requests it receives.
+func countingServer(t *testing.T, status int) (*httptest.Server, func() int) {
+	t.Helper()
+
+	var mutex sync.Mutex
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		hits++
+		mutex.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return hits
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	policy := BreakerPolicy{FailureThreshold: 2, CoolDown: time.Hour}
+
+	tests := []struct {
+		name string
+		// openedAgo is how long ago the stored breaker opened, or 0 if it is closed
+		openedAgo    time.Duration
+		failures     int
+		status       int
+		requests     int
+		wantHits     int
+		wantFailures int
+		wantOpen     bool
+	}{
+		{
+			name:         "opens after the failure threshold",
+			status:       http.StatusInternalServerError,
+			requests:     3,
+			wantHits:     4,
+			wantFailures: 2,
+			wantOpen:     true,
+		},
+		{
+			name:     "client errors are not failures",
+			status:   http.StatusNotFound,
+			requests: 3,
+			wantHits: 3,
+		},
+		{
+			name:         "open breaker fails requests during the cool-down",
+			openedAgo:    time.Minute,
+			failures:     2,
+			status:       http.StatusOK,
+			requests:     2,
+			wantHits:     0,
+			wantFailures: 2,
+			wantOpen:     true,
+		},
+		{
+			name:      "successful probe closes the breaker",
+			openedAgo: 2 * time.Hour,
+			failures:  2,
+			status:    http.StatusOK,
+			requests:  2,
+			wantHits:  2,
+		},
+		{
+			name:         "failed probe is not retried and reopens the breaker",
+			openedAgo:    2 * time.Hour,
+			failures:     2,
+			status:       http.StatusInternalServerError,
+			requests:     2,
+			wantHits:     1,
+			wantFailures: 3,
+			wantOpen:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, hits := countingServer(t, test.status)
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			upstream := req.URL.Hostname()
+
+			store := &memoryBreakerStore{states: make(map[string]BreakerState)}
+			if test.openedAgo > 0 {
+				store.states[upstream] = BreakerState{Upstream: upstream, Failures: test.failures, OpenedAt: time.Now().Add(-test.openedAgo)}
+			}
+
+			httpClient, err := New(Config{
+				Retry:        RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+				Breaker:      policy,
+				BreakerStore: store,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var lastErr error
+			for i := 0; i < test.requests; i++ {
+				resp, err := httpClient.Get(server.URL)
+				if err == nil {
+					resp.Body.Close()
+				}
+				lastErr = err
+			}
+
+			if got := hits(); got != test.wantHits {
+				t.Errorf("server received %d requests, want %d", got, test.wantHits)
+			}
+			if open := errors.Is(lastErr, ErrCircuitOpen); open != test.wantOpen {
+				t.Errorf("last request error = %v, want circuit open %t", lastErr, test.wantOpen)
+			}
+
+			state := store.states[upstream]
+			if state.Failures != test.wantFailures {
+				t.Errorf("stored failures = %d, want %d", state.Failures, test.wantFailures)
+			}
+			if open := !state.OpenedAt.IsZero(); open != test.wantOpen {
+				t.Errorf("stored breaker opened at %s, want open %t", state.OpenedAt, test.wantOpen)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveNames are substrings of header and query parameter names whose values
+// are redacted from cassettes, such as Authorization and X-CMC_PRO_API_KEY.
+var sensitiveNames = []string{"authorization", "api_key", "api-key", "apikey", "token", "secret"}
+
+// Cassette is a file of recorded HTTP interactions, used to replay a run without a network.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response, with credentials redacted.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// recordTransport sends requests to the underlying transport and saves each
+// interaction to the cassette file, which is rewritten after every request so
+// that a cancelled run still leaves a valid cassette.
+type recordTransport struct {
+	underlying http.RoundTripper
+	path       string
+	secrets    []string
+	mutex      sync.Mutex
+	cassette   Cassette
+}
+
+// replayTransport serves recorded responses instead of sending requests. Each request
+// is matched by method, redacted URL and body to the first unused matching interaction.
+type replayTransport struct {
+	secrets      []string
+	mutex        sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+func newRecordTransport(underlying http.RoundTripper, path string, secrets []string) *recordTransport {
+	return &recordTransport{underlying: underlying, path: path, secrets: secrets}
+}
+
+func newReplayTransport(path string, secrets []string) (*replayTransport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+
+	return &replayTransport{
+		secrets:      secrets,
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordedReq, err := recordRequest(req, t.secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Redaction may change the body length, so it is taken from the body on replay
+	header := redactHeader(resp.Header, t.secrets)
+	header.Del("Content-Length")
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request: recordedReq,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       redactSecrets(string(body), t.secrets),
+		},
+	})
+
+	data, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(t.path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordedReq, err := recordRequest(req, t.secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for i, interaction := range t.interactions {
+		if t.used[i] || !matches(interaction.Request, recordedReq) {
+			continue
+		}
+		t.used[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded response in cassette for %s %s", recordedReq.Method, recordedReq.URL)
+}
+
+func matches(recorded, req RecordedRequest) bool {
+	return recorded.Method == req.Method && recorded.URL == req.URL && recorded.Body == req.Body
+}
+
+// recordRequest returns the redacted request, leaving the request body readable.
+func recordRequest(req *http.Request, secrets []string) (RecordedRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return RecordedRequest{}, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return RecordedRequest{
+		Method: req.Method,
+		URL:    redactSecrets(redactURL(req.URL), secrets),
+		Header: redactHeader(req.Header, secrets),
+		Body:   redactSecrets(string(body), secrets),
+	}, nil
+}
+
+func redactHeader(header http.Header, secrets []string) http.Header {
+	redactedHeader := header.Clone()
+	for name, values := range redactedHeader {
+		for i := range values {
+			if isSensitive(name) {
+				values[i] = redacted
+			} else {
+				values[i] = redactSecrets(values[i], secrets)
+			}
+		}
+	}
+	return redactedHeader
+}
+
+// redactSecrets replaces every occurrence of the secrets in s, such as an API
+// key that is part of the PATH URL.
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
+
+func redactURL(u *url.URL) string {
+	redactedURL := *u
+	redactedURL.User = nil
+
+	query := redactedURL.Query()
+	for name, values := range query {
+		if isSensitive(name) {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+
+	return redactedURL.String()
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
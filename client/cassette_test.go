@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cr3t-api-key"
+
+func TestReplayCassette(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := new(bytes.Buffer)
+		if _, err := body.ReadFrom(r.Body); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path":%q,"body":%q,"key":%q}`, r.URL.Path, body.String(), testSecret)
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	baseURL := server.URL + "/v1/" + testSecret
+
+	type response struct {
+		Path string `json:"path"`
+		Body string `json:"body"`
+		Key  string `json:"key"`
+	}
+	requests := []struct {
+		name     string
+		post     []byte
+		endpoint string
+	}{
+		{name: "GET", endpoint: baseURL + "/balance?api_key=" + testSecret},
+		{name: "POST", endpoint: baseURL, post: []byte(`{"method":"eth_blockNumber"}`)},
+		{name: "POST with another body", endpoint: baseURL, post: []byte(`{"method":"eth_chainId"}`)},
+	}
+	send := func(httpClient *http.Client, endpoint string, post []byte) (response, error) {
+		header := http.Header{"Authorization": {"Bearer " + testSecret}}
+		if post != nil {
+			return Post[response](context.Background(), endpoint, header, post, httpClient)
+		}
+		return Get[response](context.Background(), endpoint, header, httpClient)
+	}
+
+	// Record the requests against the server
+	recordClient, err := New(Config{Record: path, Secrets: []string{testSecret}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := make([]response, len(requests))
+	for i, req := range requests {
+		recorded[i], err = send(recordClient, req.endpoint, req.post)
+		if err != nil {
+			t.Fatalf("%s: %s", req.name, err)
+		}
+	}
+	server.Close()
+
+	cassette, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(cassette), testSecret) {
+		t.Errorf("cassette contains the secret:\n%s", cassette)
+	}
+
+	// Replay the requests in a different order, with the server closed
+	replayClient, err := New(Config{
+		Replay:  path,
+		Retry:   RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		Secrets: []string{testSecret},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{2, 0, 1} {
+		req := requests[i]
+		t.Run(req.name, func(t *testing.T) {
+			got, err := send(replayClient, req.endpoint, req.post)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Secrets are redacted from the recorded responses
+			want := recorded[i]
+			want.Path = redactSecrets(want.Path, []string{testSecret})
+			want.Key = redacted
+			if got != want {
+				t.Errorf("replayed %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	// Each interaction is replayed once, and unrecorded requests are not sent
+	tests := []struct {
+		name     string
+		endpoint string
+		post     []byte
+	}{
+		{name: "replayed request", endpoint: requests[0].endpoint},
+		{name: "unrecorded body", endpoint: baseURL, post: []byte(`{"method":"eth_gasPrice"}`)},
+		{name: "unrecorded path", endpoint: server.URL + "/v2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := send(replayClient, test.endpoint, test.post)
+			if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+				t.Errorf("error = %v, want no recorded response", err)
+			}
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) {
+				t.Errorf("request reached an upstream: %s", httpErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxResponseSize caps the response bodies read by Get, Post and GetXML.
+	maxResponseSize = 10 << 20
+	// maxErrorBodySize caps the response body kept in an HTTPError.
+	maxErrorBodySize = 1 << 10
+)
+
+// ErrResponseTooLarge is returned for response bodies larger than the maximum response size.
+var ErrResponseTooLarge = fmt.Errorf("response body exceeds %d bytes", maxResponseSize)
+
+// HTTPError is returned for responses with an unexpected status code. Code and
+// Message are the error fields decoded from the body, if the upstream sent any,
+// such as CoinMarketCap's status.error_message or a JSON-RPC error.
+type HTTPError struct {
+	Method     string
+	Host       string
+	Path       string
+	StatusCode int
+	// Body is the start of the response body, truncated to maxErrorBodySize.
+	Body    string
+	Code    string
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s%s: %d %s", e.Method, e.Host, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	switch {
+	case e.Message != "" && e.Code != "":
+		msg += fmt.Sprintf(": %s (code %s)", e.Message, e.Code)
+	case e.Message != "":
+		msg += ": " + e.Message
+	case e.Body != "":
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// IsAuth reports whether the request was rejected because of a missing or
+// invalid API key, or an API plan that does not include the request.
+func (e *HTTPError) IsAuth() bool {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden:
+		return true
+	}
+	return false
+}
+
+// IsRateLimited reports whether the request was rejected by a rate limit.
+func (e *HTTPError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsNotFound reports whether the requested resource does not exist, such as an
+// account that is not in a module's state.
+func (e *HTTPError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsInvalidRequest reports whether the request was rejected as invalid,
+// e.g. because it was for an unknown symbol or address.
+func (e *HTTPError) IsInvalidRequest() bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// newHTTPError reads the start of the response body and decodes its error fields.
+func newHTTPError(req *http.Request, resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+	truncated := len(body) > maxErrorBodySize
+	if truncated {
+		body = body[:maxErrorBodySize]
+	}
+
+	httpErr := &HTTPError{
+		Method:     req.Method,
+		Host:       req.URL.Host,
+		Path:       req.URL.Path,
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+	if truncated {
+		httpErr.Body += "…"
+	} else {
+		httpErr.Code, httpErr.Message = decodeErrorFields(body)
+	}
+
+	return httpErr
+}
+
+// decodeErrorFields returns the error code and message of a JSON error body.
+// It understands CoinMarketCap and CoinGecko status objects, JSON-RPC errors,
+// gRPC gateway errors and plain error strings.
+func decodeErrorFields(body []byte) (string, string) {
+	var fields struct {
+		Status *struct {
+			ErrorCode    json.RawMessage `json:"error_code"`
+			ErrorMessage string          `json:"error_message"`
+		} `json:"status"`
+		Error   json.RawMessage `json:"error"`
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return "", ""
+	}
+
+	if fields.Status != nil && fields.Status.ErrorMessage != "" {
+		return rawString(fields.Status.ErrorCode), fields.Status.ErrorMessage
+	}
+
+	if len(fields.Error) > 0 {
+		var message string
+		if err := json.Unmarshal(fields.Error, &message); err == nil {
+			return rawString(fields.Code), message
+		}
+		var rpcError struct {
+			Code    json.RawMessage `json:"code"`
+			Message string          `json:"message"`
+		}
+		if err := json.Unmarshal(fields.Error, &rpcError); err == nil && rpcError.Message != "" {
+			return rawString(rpcError.Code), rpcError.Message
+		}
+	}
+
+	return rawString(fields.Code), fields.Message
+}
+
+// rawString returns a raw JSON string or number as text.
+func rawString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if raw == nil || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+	return string(raw)
+}
+
+// cappedReader reads up to maxResponseSize bytes, then fails with ErrResponseTooLarge.
+type cappedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func newCappedReader(reader io.Reader) *cappedReader {
+	return &cappedReader{reader: reader, remaining: maxResponseSize}
+}
+
+func (r *cappedReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		// Check whether the body ends exactly at the limit
+		var b [1]byte
+		n, err := r.reader.Read(b[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
@@ -2,140 +2,195 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
+	"encoding/xml"
 	"io"
 	"net/http"
-	"time"
 )
 
-var errResponseNotOK error = errors.New("Response not OK")
-
-type retryTransport struct {
-	underlying http.RoundTripper
-	retries    int
+// Config configures the HTTP client used for all upstreams.
+type Config struct {
+	// Retry is the retry policy of upstreams without their own policy.
+	// The zero value uses DefaultRetryPolicy.
+	Retry RetryPolicy
+	// Upstreams maps a PATH service ID or a host to its retry policy.
+	Upstreams map[string]RetryPolicy
+	// RateLimits maps a PATH service ID or a host to its rate limit.
+	RateLimits map[string]RateLimit
+	// Transport configures the connections to upstreams without their own settings.
+	// The zero value uses DefaultTransportConfig.
+	Transport TransportConfig
+	// Transports maps a PATH service ID or a host to its connection settings.
+	Transports map[string]TransportConfig
+	// Breaker is the circuit breaker policy of upstreams without their own policy.
+	// The zero value uses DefaultBreakerPolicy.
+	Breaker BreakerPolicy
+	// Breakers maps a PATH service ID or a host to its circuit breaker policy.
+	Breakers map[string]BreakerPolicy
+	// BreakerStore is optional. If set, each upstream has a circuit breaker whose
+	// state is stored with it. Breakers are not used when replaying.
+	BreakerStore BreakerStore
+	// Debug is optional. If set, the time spent waiting on rate limits and
+	// changes of circuit breaker state are written to it.
+	Debug io.Writer
+	// Record is optional. If set, every request and response is saved to a cassette file at this path.
+	Record string
+	// Replay is optional. If set, responses are served from the cassette file at
+	// this path, and no requests are sent.
+	Replay string
+	// Tracer is optional. If set, every request is recorded with it.
+	Tracer *Tracer
+	// Secrets are redacted wherever they appear in recorded requests and responses,
+	// in addition to Authorization headers and API key headers and query parameters.
+	Secrets []string
 }
 
-func New() *http.Client {
-	return &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &retryTransport{
-			underlying: http.DefaultTransport,
-			retries:    3,
-		},
+// New creates an HTTP client that retries failed requests according to the
+// retry policy of each upstream. There is no overall request timeout, so that
+// backoff and Retry-After delays are not cut short. Instead, each attempt is
+// bounded by the request timeout of the upstream, and all attempts by the context
+// of the request.
+func New(config Config) (*http.Client, error) {
+	policy := config.Retry
+	if policy == (RetryPolicy{}) {
+		policy = DefaultRetryPolicy
 	}
-}
 
-func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	rt := t.underlying
-	if rt == nil {
-		rt = http.DefaultTransport
+	transportConfig := config.Transport
+	if transportConfig == (TransportConfig{}) {
+		transportConfig = DefaultTransportConfig
+	}
+	transport, err := newUpstreamTransport(transportConfig, config.Transports)
+	if err != nil {
+		return nil, err
 	}
 
-	var resp *http.Response
-	var err error
-
-	// Cache request body
-	var bodyBytes []byte
-	if req.Body != nil {
-		bodyBytes, err = io.ReadAll(req.Body)
+	var underlying http.RoundTripper = transport
+	switch {
+	case config.Replay != "":
+		replay, err := newReplayTransport(config.Replay, config.Secrets)
 		if err != nil {
 			return nil, err
 		}
+		underlying = replay
+	case config.Record != "":
+		underlying = newRecordTransport(transport, config.Record, config.Secrets)
 	}
 
-	for i := 0; i <= t.retries; i++ {
-		// Recreate body reader
-		if bodyBytes != nil {
-			req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	// Replayed responses are not rate limited
+	if len(config.RateLimits) > 0 && config.Replay == "" {
+		limiters := make(map[string]*rateLimiter)
+		for upstream, limit := range config.RateLimits {
+			limiters[upstream] = newRateLimiter(limit)
 		}
-
-		resp, err = rt.RoundTrip(req)
-		if err == nil && resp.StatusCode < 500 {
-			break
+		underlying = &rateLimitTransport{
+			underlying: underlying,
+			limiters:   limiters,
+			debug:      config.Debug,
 		}
+	}
 
-		if i < t.retries {
-			time.Sleep(time.Duration(i*i) * 100 * time.Millisecond)
+	var roundTripper http.RoundTripper = &retryTransport{
+		underlying: underlying,
+		policy:     policy,
+		upstreams:  config.Upstreams,
+	}
+	if config.BreakerStore != nil && config.Replay == "" {
+		breakerPolicy := config.Breaker
+		if breakerPolicy == (BreakerPolicy{}) {
+			breakerPolicy = DefaultBreakerPolicy
+		}
+		breaker, err := newBreakerTransport(roundTripper, breakerPolicy, config.Breakers, config.BreakerStore, config.Debug)
+		if err != nil {
+			return nil, err
 		}
+		roundTripper = breaker
 	}
-
-	if err != nil {
-		return nil, err
+	if config.Tracer != nil {
+		roundTripper = &traceTransport{underlying: roundTripper, tracer: config.Tracer}
 	}
 
-	return resp, nil
+	return &http.Client{Transport: roundTripper}, nil
 }
 
 // Generic HTTP GET request
-func Get[T any](endpoint string, header http.Header, httpClient *http.Client) (T, error) {
+func Get[T any](ctx context.Context, endpoint string, header http.Header, httpClient *http.Client) (T, error) {
+	data, _, err := GetWithHeader[T](ctx, endpoint, header, httpClient)
+	return data, err
+}
+
+// Generic HTTP GET request that also returns the response header
+func GetWithHeader[T any](ctx context.Context, endpoint string, header http.Header, httpClient *http.Client) (T, http.Header, error) {
 	var data T
 
 	// Create a new request
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return data, err
+		return data, nil, err
 	}
 
-	// Set headers
-	req.Header = header
-
-	// Send the request
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return data, err
-	}
-	defer resp.Body.Close()
+	respHeader, err := do(req, header, httpClient, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&data)
+	})
+	return data, respHeader, err
+}
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		code := resp.StatusCode
-		text := http.StatusText(code)
-		return data, fmt.Errorf("%s. %d %s", errResponseNotOK, code, text)
-	}
+// Generic HTTP POST request
+func Post[T any](ctx context.Context, endpoint string, header http.Header, postData []byte, httpClient *http.Client) (T, error) {
+	var data T
 
-	// Decode response body
-	err = json.NewDecoder(resp.Body).Decode(&data)
+	// Create a new request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(postData))
 	if err != nil {
 		return data, err
 	}
 
-	return data, nil
+	_, err = do(req, header, httpClient, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&data)
+	})
+	return data, err
 }
 
-// Generic HTTP POST request
-func Post[T any](endpoint string, header http.Header, postData []byte, httpClient *http.Client) (T, error) {
+// Generic HTTP GET request for XML responses
+func GetXML[T any](ctx context.Context, endpoint string, header http.Header, httpClient *http.Client) (T, error) {
 	var data T
 
 	// Create a new request
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(postData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return data, err
 	}
 
+	_, err = do(req, header, httpClient, func(body io.Reader) error {
+		return xml.NewDecoder(body).Decode(&data)
+	})
+	return data, err
+}
+
+// do sends the request and decodes the response body, capped to maxResponseSize,
+// with decode, and returns the response header. GET requests must return 200 and
+// POST requests any 2xx status.
+func do(req *http.Request, header http.Header, httpClient *http.Client, decode func(body io.Reader) error) (http.Header, error) {
 	// Set headers
 	req.Header = header
 
 	// Send the request
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return data, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Check response status
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		code := resp.StatusCode
-		text := http.StatusText(code)
-		return data, fmt.Errorf("%s. %d %s", errResponseNotOK, code, text)
+	ok := resp.StatusCode == http.StatusOK
+	if req.Method == http.MethodPost {
+		ok = resp.StatusCode >= 200 && resp.StatusCode < 300
 	}
-
-	// Decode response body
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	if err != nil {
-		return data, err
+	if !ok {
+		return nil, newHTTPError(req, resp)
 	}
 
-	return data, nil
+	// Decode response body
+	return resp.Header, decode(newCappedReader(resp.Body))
 }
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit configures the token bucket of an upstream.
+type RateLimit struct {
+	// RequestsPerSecond is the rate the bucket refills at.
+	RequestsPerSecond float64
+	// Burst is the bucket size, the number of requests that may be sent at once.
+	Burst int
+}
+
+// rateLimiter is a token bucket. Requests that find the bucket empty reserve
+// a future token and wait for it, so waiting requests are sent in order.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	burst := float64(max(limit.Burst, 1))
+	return &rateLimiter{
+		rate:   limit.RequestsPerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token from the bucket, waiting until one is available, and
+// returns the time spent waiting.
+func (l *rateLimiter) wait(ctx context.Context) (time.Duration, error) {
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	var delay time.Duration
+	if l.tokens < 1 {
+		delay = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+	l.tokens--
+	l.mutex.Unlock()
+
+	if delay == 0 {
+		return 0, nil
+	}
+
+	select {
+	case <-time.After(delay):
+		return delay, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// rateLimitTransport waits on the rate limiter of each request's upstream
+// before sending it, including before each retry.
+type rateLimitTransport struct {
+	underlying http.RoundTripper
+	limiters   map[string]*rateLimiter
+	debug      io.Writer
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if upstream, limiter := t.limiterFor(req); limiter != nil {
+		waited, err := limiter.wait(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		if waited > 0 && t.debug != nil {
+			fmt.Fprintf(t.debug, "⏳ Waited %s for the %s rate limit\n", waited.Round(time.Millisecond), upstream)
+		}
+	}
+	return t.underlying.RoundTrip(req)
+}
+
+// limiterFor returns the rate limiter of the request's upstream, which is its
+// PATH service ID if it has one, or else its host.
+func (t *rateLimitTransport) limiterFor(req *http.Request) (string, *rateLimiter) {
+	if serviceID := req.Header.Get(serviceIDHeader); serviceID != "" {
+		if limiter, ok := t.limiters[serviceID]; ok {
+			return serviceID, limiter
+		}
+	}
+	host := req.URL.Hostname()
+	return host, t.limiters[host]
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		limit    RateLimit
+		requests int
+		minTime  time.Duration
+		maxTime  time.Duration
+	}{
+		{name: "burst is sent at once", limit: RateLimit{RequestsPerSecond: 1, Burst: 3}, requests: 3, maxTime: 500 * time.Millisecond},
+		{name: "requests after the burst wait for the rate", limit: RateLimit{RequestsPerSecond: 10, Burst: 1}, requests: 4, minTime: 300 * time.Millisecond, maxTime: time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			httpClient, err := New(Config{
+				RateLimits: map[string]RateLimit{req.URL.Hostname(): test.limit},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			for i := 0; i < test.requests; i++ {
+				resp, err := httpClient.Get(server.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+
+			if elapsed := time.Since(start); elapsed < test.minTime || elapsed > test.maxTime {
+				t.Errorf("%d requests took %s, want between %s and %s", test.requests, elapsed, test.minTime, test.maxTime)
+			}
+		})
+	}
+}
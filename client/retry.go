@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// serviceIDHeader is the header PATH uses to route a request to a service.
+	serviceIDHeader = "Target-Service-Id"
+	// idempotencyKeyHeader marks a request as safe to retry. As in net/http,
+	// a nil value marks the request without sending the header.
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// RetryPolicy configures how failed requests to an upstream are retried.
+// Requests are retried on network errors, 5xx responses and 429 responses.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry, doubled on each retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and any Retry-After delay.
+	MaxDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  200 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// MarkIdempotent marks a request with a non-idempotent method, such as a
+// read-only JSON-RPC POST, as safe to retry.
+func MarkIdempotent(header http.Header) {
+	if _, ok := header[idempotencyKeyHeader]; !ok {
+		header[idempotencyKeyHeader] = nil
+	}
+}
+
+type retryTransport struct {
+	underlying http.RoundTripper
+	policy     RetryPolicy
+	upstreams  map[string]RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.underlying
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	policy := t.policyFor(req)
+	if !isIdempotent(req) || isProbe(req.Context()) {
+		policy.MaxRetries = 0
+	}
+
+	var resp *http.Response
+	var err error
+
+	// Cache request body
+	var bodyBytes []byte
+	if req.Body != nil {
+		bodyBytes, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		// Recreate body reader
+		if bodyBytes != nil {
+			req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		}
+
+		countAttempt(req.Context())
+		resp, err = rt.RoundTrip(req)
+		if !shouldRetry(resp, err) || attempt >= policy.MaxRetries || req.Context().Err() != nil {
+			break
+		}
+
+		delay := policy.backoff(attempt)
+		if err == nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(retryAfter, policy.MaxDelay)
+			}
+			// Close the body of the failed response before retrying
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// policyFor returns the retry policy of the request's upstream, which is its
+// PATH service ID if it has one, or else its host.
+func (t *retryTransport) policyFor(req *http.Request) RetryPolicy {
+	if serviceID := req.Header.Get(serviceIDHeader); serviceID != "" {
+		if policy, ok := t.upstreams[serviceID]; ok {
+			return policy
+		}
+	}
+	if policy, ok := t.upstreams[req.URL.Hostname()]; ok {
+		return policy
+	}
+	return t.policy
+}
+
+// backoff returns a random delay of up to BaseDelay * 2^attempt, capped at MaxDelay.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 && p.BaseDelay<<attempt < p.MaxDelay {
+		delay = p.BaseDelay << attempt
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := req.Header[idempotencyKeyHeader]
+	return ok
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryOnlyRetriesIdempotentRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		idempotent bool
+		wantHits   int
+	}{
+		{name: "GET", method: http.MethodGet, wantHits: 3},
+		{name: "PUT", method: http.MethodPut, wantHits: 3},
+		{name: "POST", method: http.MethodPost, wantHits: 1},
+		{name: "POST marked idempotent", method: http.MethodPost, idempotent: true, wantHits: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			hits := 0
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := new(bytes.Buffer)
+				if _, err := body.ReadFrom(r.Body); err != nil {
+					t.Error(err)
+				}
+				mutex.Lock()
+				hits++
+				bodies = append(bodies, body.String())
+				mutex.Unlock()
+				if _, ok := r.Header[idempotencyKeyHeader]; ok {
+					t.Error("the idempotency marker was sent as a header")
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			httpClient, err := New(Config{
+				Retry: RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(test.method, server.URL, strings.NewReader(`{"method":"eth_call"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.idempotent {
+				MarkIdempotent(req.Header)
+			}
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if hits != test.wantHits {
+				t.Errorf("server received %d requests, want %d", hits, test.wantHits)
+			}
+			// Each retry resends the request body
+			for i, body := range bodies {
+				if body != `{"method":"eth_call"}` {
+					t.Errorf("attempt %d body = %q, want the request body", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	const maxDelay = 300 * time.Millisecond
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		minWait    time.Duration
+		maxWait    time.Duration
+	}{
+		{name: "seconds", retryAfter: "0", maxWait: maxDelay / 2},
+		{name: "seconds capped at the max delay", retryAfter: "5", minWait: maxDelay, maxWait: time.Second},
+		{name: "HTTP date capped at the max delay", retryAfter: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), minWait: maxDelay, maxWait: time.Second},
+		{name: "HTTP date in the past", retryAfter: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), maxWait: maxDelay / 2},
+		{name: "invalid value falls back to the backoff", retryAfter: "soon", maxWait: maxDelay / 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var times []time.Time
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				times = append(times, time.Now())
+				first := len(times) == 1
+				mutex.Unlock()
+
+				if first {
+					w.Header().Set("Retry-After", test.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			httpClient, err := New(Config{
+				Retry: RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: maxDelay},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := httpClient.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if len(times) != 2 {
+				t.Fatalf("server received %d requests, want 2", len(times))
+			}
+			if wait := times[1].Sub(times[0]); wait < test.minWait || wait > test.maxWait {
+				t.Errorf("waited %s before retrying, want between %s and %s", wait, test.minWait, test.maxWait)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Call is a traced request to an upstream, including all of its retries.
+type Call struct {
+	Time      time.Time
+	Method    string
+	Host      string
+	ServiceID string
+	Status    int
+	Attempts  int
+	Latency   time.Duration
+	Bytes     int64
+	Err       string
+}
+
+// Upstream returns the name of the call's upstream, which is its PATH
+// service ID if it has one, or else its host.
+func (c Call) Upstream() string {
+	if c.ServiceID != "" {
+		return c.ServiceID
+	}
+	return c.Host
+}
+
+func (c Call) String() string {
+	result := fmt.Sprintf("%d", c.Status)
+	if c.Err != "" {
+		result = c.Err
+	}
+	return fmt.Sprintf("%s %s (%s) %s, %d attempt(s), %s, %d bytes",
+		c.Method, c.Host, c.Upstream(), result, c.Attempts, c.Latency.Round(time.Millisecond), c.Bytes)
+}
+
+// UpstreamSummary is the total of all calls to an upstream.
+type UpstreamSummary struct {
+	Upstream     string
+	Calls        int
+	Errors       int
+	Attempts     int
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+	Bytes        int64
+}
+
+// Tracer records every request sent by the HTTP client.
+type Tracer struct {
+	mutex   sync.Mutex
+	calls   []Call
+	verbose io.Writer
+}
+
+// NewTracer creates a tracer. If verbose is not nil, each call is written to it as it completes.
+func NewTracer(verbose io.Writer) *Tracer {
+	return &Tracer{verbose: verbose}
+}
+
+// Calls returns a copy of the traced calls, which is not changed by calls traced later.
+func (t *Tracer) Calls() []Call {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]Call(nil), t.calls...)
+}
+
+// Summary returns the total of the traced calls to each upstream, sorted by upstream.
+func (t *Tracer) Summary() []UpstreamSummary {
+	summaries := make(map[string]*UpstreamSummary)
+	for _, call := range t.Calls() {
+		summary, ok := summaries[call.Upstream()]
+		if !ok {
+			summary = &UpstreamSummary{Upstream: call.Upstream()}
+			summaries[call.Upstream()] = summary
+		}
+		summary.Calls++
+		if call.Err != "" || call.Status < 200 || call.Status >= 300 {
+			summary.Errors++
+		}
+		summary.Attempts += call.Attempts
+		summary.TotalLatency += call.Latency
+		summary.MaxLatency = max(summary.MaxLatency, call.Latency)
+		summary.Bytes += call.Bytes
+	}
+
+	result := make([]UpstreamSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Upstream < result[j].Upstream })
+	return result
+}
+
+func (t *Tracer) record(call Call) {
+	t.mutex.Lock()
+	t.calls = append(t.calls, call)
+	t.mutex.Unlock()
+
+	if t.verbose != nil {
+		fmt.Fprintf(t.verbose, "🔎 %s\n", call)
+	}
+}
+
+// attemptsKey is the context key of the attempt counter of a traced request.
+type attemptsKey struct{}
+
+// countAttempt increments the attempt counter of a traced request.
+func countAttempt(ctx context.Context) {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*int); ok {
+		*attempts++
+	}
+}
+
+// traceTransport records each request once its response body is closed,
+// so that the latency and byte count include reading the body.
+type traceTransport struct {
+	underlying http.RoundTripper
+	tracer     *Tracer
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := new(int)
+	req = req.WithContext(context.WithValue(req.Context(), attemptsKey{}, attempts))
+
+	call := Call{
+		Time:      time.Now(),
+		Method:    req.Method,
+		Host:      req.URL.Hostname(),
+		ServiceID: req.Header.Get(serviceIDHeader),
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+	if err != nil {
+		call.Attempts = *attempts
+		call.Latency = time.Since(call.Time)
+		call.Err = err.Error()
+		t.tracer.record(call)
+		return nil, err
+	}
+
+	call.Status = resp.StatusCode
+	resp.Body = &tracedBody{
+		ReadCloser: resp.Body,
+		done: func(bytes int64) {
+			call.Attempts = *attempts
+			call.Latency = time.Since(call.Time)
+			call.Bytes = bytes
+			t.tracer.record(call)
+		},
+	}
+	return resp, nil
+}
+
+// tracedBody counts the bytes read from a response body and reports them once on Close.
+type tracedBody struct {
+	io.ReadCloser
+	bytes int64
+	once  sync.Once
+	done  func(bytes int64)
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.bytes) })
+	return err
+}
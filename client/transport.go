@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TransportConfig configures the connections to an upstream.
+type TransportConfig struct {
+	// Proxy is the URL of an HTTP, HTTPS or SOCKS5 proxy, e.g. "socks5://localhost:1080".
+	// If empty, the proxy is taken from the HTTP_PROXY and HTTPS_PROXY environment variables.
+	Proxy string
+	// CAFile is a PEM bundle of CA certificates trusted in addition to the system ones.
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key used for mTLS.
+	CertFile string
+	KeyFile  string
+
+	// RequestTimeout bounds each attempt of a request, from sending it to reading the
+	// last byte of the response body, so that a body that stalls cannot hang a run.
+	RequestTimeout        time.Duration
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	// KeepAlive is the interval of TCP keep-alive probes.
+	KeepAlive time.Duration
+	// DisableKeepAlives closes each connection after a single request.
+	DisableKeepAlives bool
+}
+
+var DefaultTransportConfig = TransportConfig{
+	RequestTimeout:        10 * time.Second,
+	DialTimeout:           30 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 10 * time.Second,
+	IdleConnTimeout:       90 * time.Second,
+	KeepAlive:             30 * time.Second,
+}
+
+// errRequestTimeout is returned for attempts that exceed the request timeout. It does
+// not wrap context.DeadlineExceeded, which is kept for the deadline of the run.
+var errRequestTimeout = errors.New("request timeout exceeded")
+
+// newTransport creates an HTTP transport with the connection settings of config.
+func newTransport(config TransportConfig) (http.RoundTripper, error) {
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: config.KeepAlive,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	transport.IdleConnTimeout = config.IdleConnTimeout
+	transport.DisableKeepAlives = config.DisableKeepAlives
+
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", config.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.CAFile == "" && config.CertFile == "" {
+		return withRequestTimeout(transport, config.RequestTimeout), nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if config.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return withRequestTimeout(transport, config.RequestTimeout), nil
+}
+
+// timeoutTransport bounds each attempt of a request by the request timeout. It is
+// the innermost transport, so that the time spent waiting on rate limits and retry
+// backoff is not counted.
+type timeoutTransport struct {
+	underlying http.RoundTripper
+	timeout    time.Duration
+}
+
+func withRequestTimeout(transport http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	if timeout <= 0 {
+		return transport
+	}
+	return &timeoutTransport{underlying: transport, timeout: timeout}
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	resp, err := t.underlying.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, t.timeoutErr(ctx, req, err)
+	}
+
+	// The timeout keeps running while the body is read, until it is closed
+	resp.Body = &timeoutBody{ReadCloser: resp.Body, transport: t, req: req, ctx: ctx, cancel: cancel}
+	return resp, nil
+}
+
+// timeoutErr replaces the error of an attempt that exceeded the request timeout,
+// unless the request's own context is done.
+func (t *timeoutTransport) timeoutErr(ctx context.Context, req *http.Request, err error) error {
+	if ctx.Err() != nil && req.Context().Err() == nil {
+		return fmt.Errorf("%s %s%s: %w after %s", req.Method, req.URL.Host, req.URL.Path, errRequestTimeout, t.timeout)
+	}
+	return err
+}
+
+type timeoutBody struct {
+	io.ReadCloser
+	transport *timeoutTransport
+	req       *http.Request
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.transport.timeoutErr(b.ctx, b.req, err)
+	}
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// upstreamTransport sends each request with the transport of its upstream,
+// which is its PATH service ID if it has one, or else its host.
+type upstreamTransport struct {
+	underlying http.RoundTripper
+	upstreams  map[string]http.RoundTripper
+}
+
+func newUpstreamTransport(config TransportConfig, upstreams map[string]TransportConfig) (http.RoundTripper, error) {
+	underlying, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(upstreams) == 0 {
+		return underlying, nil
+	}
+
+	transports := make(map[string]http.RoundTripper)
+	for upstream, upstreamConfig := range upstreams {
+		transport, err := newTransport(upstreamConfig)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", upstream, err)
+		}
+		transports[upstream] = transport
+	}
+
+	return &upstreamTransport{underlying: underlying, upstreams: transports}, nil
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if serviceID := req.Header.Get(serviceIDHeader); serviceID != "" {
+		if transport, ok := t.upstreams[serviceID]; ok {
+			return transport.RoundTrip(req)
+		}
+	}
+	if transport, ok := t.upstreams[req.URL.Hostname()]; ok {
+		return transport.RoundTrip(req)
+	}
+	return t.underlying.RoundTrip(req)
+}
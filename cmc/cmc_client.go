@@ -1,130 +1,213 @@
 package cmc
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/commoddity/bank-informer/client"
 )
 
-const cmcURL = "https://pro-api.coinmarketcap.com/v1/cryptocurrency/quotes/latest?symbol=%s&convert=%s"
+const cmcURL = "https://pro-api.coinmarketcap.com/v2/cryptocurrency/quotes/latest?%s=%s&convert=%s"
+
+// defaultIDs maps symbols to CoinMarketCap IDs, since tickers are not unique on CoinMarketCap.
+var defaultIDs = map[string]string{
+	"BTC":  "1",
+	"ETH":  "1027",
+	"USDT": "825",
+	"USDC": "3408",
+	"WBTC": "3717",
+	"SOL":  "5426",
+	"POKT": "11823",
+}
 
 type Config struct {
-	CMCAPIKey         string
-	ConvertCurrencies []string
-	HttpClient        *http.Client
+	CMCAPIKey string
+	// IDs maps symbols to a CoinMarketCap ID or slug, and overrides the default IDs.
+	IDs map[string]string
+	// Credits is optional. If set, the credits used by each call are recorded,
+	// and MonthlyCreditBudget limits the credits used per month if it is not 0.
+	Credits             CreditStore
+	MonthlyCreditBudget int
+	HttpClient          *http.Client
 }
 
+// Client is a price provider backed by the CoinMarketCap API.
 type Client struct {
-	Config            Config
-	HttpClient        *http.Client
-	convertCurrencies []string
-	progressChan      chan string
-	mutex             *sync.Mutex
-	waitGroup         *sync.WaitGroup
+	Config     Config
+	HttpClient *http.Client
+	ids        map[string]string
+}
+
+type cmcQuote struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+	Slug   string `json:"slug"`
+	Quote  map[string]struct {
+		Price float64 `json:"price"`
+	} `json:"quote"`
 }
 
+// cmcResult is the response to an id or slug query, keyed by CoinMarketCap ID.
 type cmcResult struct {
-	Data map[string]struct {
-		Name   string `json:"name"`
-		Symbol string `json:"symbol"`
-		Quote  map[string]struct {
-			Price float64 `json:"price"`
-		} `json:"quote"`
-	} `json:"data"`
+	cmcStatus
+	Data map[string]cmcQuote `json:"data"`
 }
 
-func NewClient(config Config, progressChan chan string, mutex *sync.Mutex, waitGroup *sync.WaitGroup) *Client {
+// cmcSymbolResult is the response to a symbol query, where each symbol
+// maps to every asset with that ticker.
+type cmcSymbolResult struct {
+	cmcStatus
+	Data map[string][]cmcQuote `json:"data"`
+}
+
+// statusResult is a response that includes the status object.
+type statusResult interface {
+	status() Status
+}
+
+func NewClient(config Config) *Client {
+	ids := make(map[string]string)
+	for symbol, id := range defaultIDs {
+		ids[symbol] = id
+	}
+	for symbol, id := range config.IDs {
+		ids[symbol] = id
+	}
+
 	return &Client{
-		Config:            config,
-		HttpClient:        config.HttpClient,
-		convertCurrencies: config.ConvertCurrencies,
-		progressChan:      progressChan,
-		mutex:             mutex,
-		waitGroup:         waitGroup,
+		Config:     config,
+		HttpClient: config.HttpClient,
+		ids:        ids,
 	}
 }
 
-func (c *Client) GetAllExchangeRates(balances map[string]float64) (map[string]map[string]float64, error) {
-	exchangeRates := make(map[string]map[string]float64)
-	errorChan := make(chan error, len(c.convertCurrencies))
-
-	// For each currency in the list of currencies to convert
-	for _, convertCurrency := range c.convertCurrencies {
-		c.waitGroup.Add(1)
-		go func(currency string) {
-			defer c.waitGroup.Done()
+func (c *Client) Name() string {
+	return providerName
+}
 
-			// Retrieve and store the exchange rates for the current currency
-			currencyExchangeRates, err := c.getExchangeRates(balances, currency)
-			if err != nil {
-				errorChan <- err
-				return
-			}
+// GetQuotes returns the CoinMarketCap price of each symbol in the convert currency.
+// Symbols with a known ID or slug are looked up by it. Other symbols are looked up
+// by ticker, and an error is returned if the ticker matches more than one asset.
+// The free plan only allows one convert currency per request.
+func (c *Client) GetQuotes(ctx context.Context, symbols []string, convertCurrency string) (map[string]float64, error) {
+	symbolsByID, symbolsBySlug, unmappedSymbols := c.groupSymbols(symbols)
 
-			c.mutex.Lock()
-			// Add the retrieved exchange rates to the map of exchange rates
-			exchangeRates[currency] = currencyExchangeRates
-			c.mutex.Unlock()
+	prices := make(map[string]float64)
 
-			c.progressChan <- currency
-		}(convertCurrency)
+	if len(symbolsByID) > 0 {
+		cmcRes, err := getQuotes[cmcResult](ctx, c, "id", keys(symbolsByID), convertCurrency)
+		if err != nil {
+			return nil, err
+		}
+		for id, data := range cmcRes.Data {
+			for _, symbol := range symbolsByID[id] {
+				prices[symbol] = data.Quote[convertCurrency].Price
+			}
+		}
 	}
 
-	c.waitGroup.Wait()
-	close(errorChan)
+	if len(symbolsBySlug) > 0 {
+		cmcRes, err := getQuotes[cmcResult](ctx, c, "slug", keys(symbolsBySlug), convertCurrency)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range cmcRes.Data {
+			for _, symbol := range symbolsBySlug[data.Slug] {
+				prices[symbol] = data.Quote[convertCurrency].Price
+			}
+		}
+	}
 
-	// Check if there were any errors
-	if len(errorChan) > 0 {
-		return nil, <-errorChan
+	if len(unmappedSymbols) > 0 {
+		cmcRes, err := getQuotes[cmcSymbolResult](ctx, c, "symbol", unmappedSymbols, convertCurrency)
+		var httpErr *client.HTTPError
+		if errors.As(err, &httpErr) && httpErr.IsInvalidRequest() {
+			return nil, fmt.Errorf("%w, set the IDs of symbols unknown to CoinMarketCap in cmc_ids", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for symbol, matches := range cmcRes.Data {
+			if len(matches) > 1 {
+				return nil, ambiguousSymbolError(symbol, matches)
+			}
+			if len(matches) == 1 {
+				prices[symbol] = matches[0].Quote[convertCurrency].Price
+			}
+		}
 	}
 
-	return exchangeRates, nil
+	return prices, nil
 }
 
-func (c *Client) getExchangeRates(balances map[string]float64, convertCurrency string) (map[string]float64, error) {
-	url := fmt.Sprintf(cmcURL, getCurrencyKeys(balances), convertCurrency)
+// groupSymbols splits the symbols into those with a known ID, keyed by ID, those with
+// a known slug, keyed by slug, and those that must be looked up by ticker. Several
+// symbols may share an ID or slug, such as a wrapped token priced as its asset.
+func (c *Client) groupSymbols(symbols []string) (map[string][]string, map[string][]string, []string) {
+	symbolsByID := make(map[string][]string)
+	symbolsBySlug := make(map[string][]string)
+	var unmappedSymbols []string
+	for _, symbol := range symbols {
+		id, ok := c.ids[symbol]
+		switch {
+		case !ok:
+			unmappedSymbols = append(unmappedSymbols, symbol)
+		case isNumeric(id):
+			symbolsByID[id] = append(symbolsByID[id], symbol)
+		default:
+			symbolsBySlug[id] = append(symbolsBySlug[id], symbol)
+		}
+	}
+	return symbolsByID, symbolsBySlug, unmappedSymbols
+}
 
-	header := http.Header{}
-	header.Set("Accepts", "application/json")
-	header.Add("X-CMC_PRO_API_KEY", c.Config.CMCAPIKey)
+// getQuotes queries the latest quotes by the given param, one of "id", "slug" or "symbol".
+func getQuotes[T statusResult](ctx context.Context, c *Client, param string, values []string, convertCurrency string) (T, error) {
+	endpoint := fmt.Sprintf(cmcURL, param, url.QueryEscape(strings.Join(values, ",")), convertCurrency)
 
-	cmcRes, err := client.Get[cmcResult](url, header, c.HttpClient)
+	res, err := client.Get[T](ctx, endpoint, c.header(), c.HttpClient)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
 
-	prices := make(map[string]float64)
-	for currency, data := range cmcRes.Data {
-		prices[currency] = data.Quote[convertCurrency].Price
-	}
-
-	return prices, nil
+	err = RecordCredits(c.Config.Credits, "quotes/latest", res.status())
+	return res, err
 }
 
-func (c *Client) GetFiatValues(balances map[string]float64, fiatExchangeRates map[string]map[string]float64) map[string]float64 {
-	fiatValues := make(map[string]float64)
+func (c *Client) header() http.Header {
+	header := http.Header{}
+	header.Set("Accepts", "application/json")
+	header.Add("X-CMC_PRO_API_KEY", c.Config.CMCAPIKey)
+	return header
+}
 
-	for fiat, exchangeRates := range fiatExchangeRates {
-		for currency, balance := range balances {
-			if exchangeRate, ok := exchangeRates[currency]; ok {
-				fiatValue := balance * exchangeRate
-				roundedFiatValue := math.Round(fiatValue*100) / 100
-				fiatValues[fiat] += roundedFiatValue
-			}
-		}
+func ambiguousSymbolError(symbol string, matches []cmcQuote) error {
+	candidates := make([]string, len(matches))
+	for i, match := range matches {
+		candidates[i] = fmt.Sprintf("%s (id %d, slug %s)", match.Name, match.ID, match.Slug)
 	}
+	return fmt.Errorf("symbol %s matches multiple CoinMarketCap assets, set its ID in cmc_ids: %s",
+		symbol, strings.Join(candidates, ", "))
+}
 
-	return fiatValues
+func isNumeric(id string) bool {
+	_, err := strconv.Atoi(id)
+	return err == nil
 }
 
-func getCurrencyKeys(balances map[string]float64) string {
-	currencyKeys := make([]string, 0, len(balances))
-	for key := range balances {
-		currencyKeys = append(currencyKeys, key)
+func keys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
-	return strings.Join(currencyKeys, ",")
+	// Sort the keys so that requests are reproducible
+	sort.Strings(keys)
+	return keys
 }
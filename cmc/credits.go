@@ -0,0 +1,102 @@
+package cmc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/commoddity/bank-informer/persistence"
+)
+
+const providerName = "cmc"
+
+// creditsPerCall is the number of assets returned per credit by the quotes endpoints.
+const creditsPerCall = 100
+
+// CreditStore stores the API credits used by each CoinMarketCap call.
+type CreditStore interface {
+	WriteCreditUsage(usage persistence.CreditUsage) error
+	GetCreditUsage(provider string, since time.Time) (int, error)
+}
+
+// Status is the status object included in every CoinMarketCap response.
+type Status struct {
+	CreditCount  int    `json:"credit_count"`
+	ErrorCode    int    `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+type cmcStatus struct {
+	Status Status `json:"status"`
+}
+
+func (s cmcStatus) status() Status {
+	return s.Status
+}
+
+// RecordCredits stores the credits used by a call to the given endpoint, if store is not nil.
+func RecordCredits(store CreditStore, endpoint string, status Status) error {
+	if store == nil || status.CreditCount == 0 {
+		return nil
+	}
+	err := store.WriteCreditUsage(persistence.CreditUsage{
+		Provider: providerName,
+		Endpoint: endpoint,
+		Time:     time.Now(),
+		Credits:  status.CreditCount,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record CoinMarketCap credit usage: %w", err)
+	}
+	return nil
+}
+
+// CreditUsage returns the credits used today and this month. CoinMarketCap
+// credit limits reset at the start of each day and month in UTC.
+func CreditUsage(store CreditStore) (today, month int, err error) {
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	today, err = store.GetCreditUsage(providerName, startOfDay)
+	if err != nil {
+		return 0, 0, err
+	}
+	month, err = store.GetCreditUsage(providerName, startOfMonth)
+	if err != nil {
+		return 0, 0, err
+	}
+	return today, month, nil
+}
+
+// CheckBudget returns an error if fetching the symbols in each of the fiat currencies,
+// along with the extra credits used from CoinMarketCap by other calls such as FX price
+// conversions, would exceed the monthly credit budget. It always succeeds if no budget is set.
+func (c *Client) CheckBudget(symbols []string, fiats []string, extraCredits map[string]int) error {
+	if c.Config.Credits == nil || c.Config.MonthlyCreditBudget <= 0 {
+		return nil
+	}
+
+	_, used, err := CreditUsage(c.Config.Credits)
+	if err != nil {
+		return fmt.Errorf("failed to get CoinMarketCap credit usage: %w", err)
+	}
+
+	needed := c.estimateCredits(symbols)*len(fiats) + extraCredits[providerName]
+	if used+needed > c.Config.MonthlyCreditBudget {
+		return fmt.Errorf("CoinMarketCap monthly credit budget would be exceeded: %d of %d credits used, %d more needed",
+			used, c.Config.MonthlyCreditBudget, needed)
+	}
+	return nil
+}
+
+// estimateCredits returns the credits used by GetQuotes for the symbols in one currency,
+// which is one credit per 100 assets for each of the ID, slug and symbol queries.
+func (c *Client) estimateCredits(symbols []string) int {
+	symbolsByID, symbolsBySlug, unmappedSymbols := c.groupSymbols(symbols)
+
+	var credits int
+	for _, count := range []int{len(symbolsByID), len(symbolsBySlug), len(unmappedSymbols)} {
+		credits += (count + creditsPerCall - 1) / creditsPerCall
+	}
+	return credits
+}
@@ -0,0 +1,140 @@
+package cmc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const (
+	cmcHistoricalURL = "https://pro-api.coinmarketcap.com/v2/cryptocurrency/quotes/historical?id=%s&time_start=%s&time_end=%s&interval=%s&convert=%s"
+
+	// priceWindow is how far from a time the nearest historical price may be.
+	priceWindow = 12 * time.Hour
+)
+
+type cmcHistoricalQuote struct {
+	ID     int    `json:"id"`
+	Symbol string `json:"symbol"`
+	Quotes []struct {
+		Timestamp time.Time `json:"timestamp"`
+		Quote     map[string]struct {
+			Price float64 `json:"price"`
+		} `json:"quote"`
+	} `json:"quotes"`
+}
+
+type cmcHistoricalResult struct {
+	cmcStatus
+	// Data is either a single quote object or an object of quotes keyed by ID
+	Data json.RawMessage `json:"data"`
+}
+
+// GetHistoricalQuotes returns the CoinMarketCap daily close of each symbol with a known
+// numeric ID on the given day. The close is the last quote at or before the end of the day.
+func (c *Client) GetHistoricalQuotes(ctx context.Context, symbols []string, convertCurrency string, day time.Time) (map[string]float64, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	prices := make(map[string]float64)
+	for _, symbol := range symbols {
+		id, ok := c.ids[symbol]
+		if !ok || !isNumeric(id) {
+			continue
+		}
+
+		quote, err := c.getHistoricalQuote(ctx, symbol, id, start, end, "daily", convertCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, q := range quote.Quotes {
+			if q.Timestamp.After(end) {
+				continue
+			}
+			if price, ok := q.Quote[convertCurrency]; ok {
+				prices[symbol] = price.Price
+			}
+		}
+	}
+
+	return prices, nil
+}
+
+// GetHistoricalPricesAt returns the CoinMarketCap hourly price of the symbol nearest to
+// each of the times, or 0 if there is none within 12 hours or the symbol has no numeric ID.
+func (c *Client) GetHistoricalPricesAt(ctx context.Context, symbol, convertCurrency string, times []time.Time) ([]float64, error) {
+	prices := make([]float64, len(times))
+	id, ok := c.ids[symbol]
+	if !ok || !isNumeric(id) || len(times) == 0 {
+		return prices, nil
+	}
+
+	start, end := times[0], times[0]
+	for _, t := range times {
+		if t.Before(start) {
+			start = t
+		}
+		if t.After(end) {
+			end = t
+		}
+	}
+
+	start, end = start.Add(-priceWindow), end.Add(priceWindow)
+
+	// Historical quotes use one credit per 100 quotes returned
+	quotes := int(end.Sub(start).Hours()) + 1
+	if err := c.CheckBudget(nil, nil, map[string]int{providerName: (quotes + creditsPerCall - 1) / creditsPerCall}); err != nil {
+		return nil, err
+	}
+
+	quote, err := c.getHistoricalQuote(ctx, symbol, id, start, end, "hourly", convertCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range times {
+		var nearest time.Duration
+		for _, q := range quote.Quotes {
+			price, ok := q.Quote[convertCurrency]
+			distance := q.Timestamp.Sub(t).Abs()
+			if !ok || distance > priceWindow || (prices[i] > 0 && distance >= nearest) {
+				continue
+			}
+			prices[i] = price.Price
+			nearest = distance
+		}
+	}
+
+	return prices, nil
+}
+
+// getHistoricalQuote returns the quotes of the symbol from start to end at the interval.
+func (c *Client) getHistoricalQuote(ctx context.Context, symbol, id string, start, end time.Time, interval, convertCurrency string) (cmcHistoricalQuote, error) {
+	url := fmt.Sprintf(cmcHistoricalURL, id, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339), interval, convertCurrency)
+	cmcRes, err := client.Get[cmcHistoricalResult](ctx, url, c.header(), c.HttpClient)
+	if err != nil {
+		return cmcHistoricalQuote{}, fmt.Errorf("failed to get historical %s quotes: %w", symbol, err)
+	}
+	if err := RecordCredits(c.Config.Credits, "quotes/historical", cmcRes.Status); err != nil {
+		return cmcHistoricalQuote{}, err
+	}
+
+	return decodeHistoricalQuote(cmcRes.Data, id)
+}
+
+func decodeHistoricalQuote(data json.RawMessage, id string) (cmcHistoricalQuote, error) {
+	var quote cmcHistoricalQuote
+	if err := json.Unmarshal(data, &quote); err == nil && quote.ID != 0 {
+		return quote, nil
+	}
+
+	var quotes map[string]cmcHistoricalQuote
+	if err := json.Unmarshal(data, &quotes); err != nil {
+		return quote, fmt.Errorf("failed to decode historical quotes for id %s: %w", id, err)
+	}
+	return quotes[id], nil
+}
@@ -0,0 +1,131 @@
+package coingecko
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const (
+	publicURL = "https://api.coingecko.com/api/v3"
+	proURL    = "https://pro-api.coingecko.com/api/v3"
+
+	simplePricePath = "%s/simple/price?ids=%s&vs_currencies=%s"
+)
+
+// defaultIDs maps symbols to CoinGecko coin IDs, since CoinGecko prices are looked up by ID.
+var defaultIDs = map[string]string{
+	"BTC":  "bitcoin",
+	"ETH":  "ethereum",
+	"POKT": "pocket-network",
+	"SOL":  "solana",
+	"USDC": "usd-coin",
+	"USDT": "tether",
+	"WBTC": "wrapped-bitcoin",
+}
+
+type Config struct {
+	APIKey     string
+	Pro        bool
+	IDs        map[string]string
+	HttpClient *http.Client
+}
+
+// Client is a price provider backed by the CoinGecko API.
+type Client struct {
+	Config     Config
+	baseUrl    string
+	ids        map[string]string
+	httpClient *http.Client
+}
+
+// simplePriceResult maps coin IDs to their price in each lowercase vs currency.
+type simplePriceResult map[string]map[string]float64
+
+// NewClient creates a CoinGecko client. The public API is used unless Pro is set,
+// in which case the API key is sent as a pro key. On the public API, an API key
+// is optional and sent as a demo key.
+func NewClient(config Config) *Client {
+	ids := make(map[string]string)
+	for symbol, id := range defaultIDs {
+		ids[symbol] = id
+	}
+	for symbol, id := range config.IDs {
+		ids[symbol] = id
+	}
+
+	baseUrl := publicURL
+	if config.Pro {
+		baseUrl = proURL
+	}
+
+	return &Client{
+		Config:     config,
+		baseUrl:    baseUrl,
+		ids:        ids,
+		httpClient: config.HttpClient,
+	}
+}
+
+func (c *Client) Name() string {
+	return "coingecko"
+}
+
+// GetQuotes returns the CoinGecko price of each symbol with a known coin ID in the fiat currency.
+func (c *Client) GetQuotes(ctx context.Context, symbols []string, fiat string) (map[string]float64, error) {
+	// Several symbols may share a coin ID, such as a wrapped token priced as its asset
+	symbolsByID := make(map[string][]string)
+	var ids []string
+	for _, symbol := range symbols {
+		id, ok := c.ids[symbol]
+		if !ok {
+			continue
+		}
+		if _, ok := symbolsByID[id]; !ok {
+			ids = append(ids, id)
+		}
+		symbolsByID[id] = append(symbolsByID[id], symbol)
+	}
+
+	prices := make(map[string]float64)
+	if len(ids) == 0 {
+		return prices, nil
+	}
+
+	vsCurrency := strings.ToLower(fiat)
+	endpoint := fmt.Sprintf(simplePricePath, c.baseUrl, url.QueryEscape(strings.Join(ids, ",")), vsCurrency)
+
+	result, err := client.Get[simplePriceResult](ctx, endpoint, c.header(), c.httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, quote := range result {
+		price, ok := quote[vsCurrency]
+		if !ok {
+			continue
+		}
+		for _, symbol := range symbolsByID[id] {
+			prices[symbol] = price
+		}
+	}
+
+	return prices, nil
+}
+
+func (c *Client) header() http.Header {
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	if c.Config.APIKey != "" {
+		if c.Config.Pro {
+			header.Set("x-cg-pro-api-key", c.Config.APIKey)
+		} else {
+			header.Set("x-cg-demo-api-key", c.Config.APIKey)
+		}
+	}
+	return header
+}
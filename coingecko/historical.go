@@ -0,0 +1,103 @@
+package coingecko
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const (
+	marketChartRangePath = "%s/coins/%s/market_chart/range?vs_currency=%s&from=%d&to=%d"
+
+	// priceWindow is how far from a time the nearest historical price may be.
+	priceWindow = 12 * time.Hour
+)
+
+// marketChartResult contains [unix milliseconds, price] pairs.
+type marketChartResult struct {
+	Prices [][2]float64 `json:"prices"`
+}
+
+// GetHistoricalQuotes returns the CoinGecko daily close of each symbol with a known coin ID
+// on the given day. The close is the last price at or before the end of the day.
+func (c *Client) GetHistoricalQuotes(ctx context.Context, symbols []string, fiat string, day time.Time) (map[string]float64, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	vsCurrency := strings.ToLower(fiat)
+
+	prices := make(map[string]float64)
+	for _, symbol := range symbols {
+		id, ok := c.ids[symbol]
+		if !ok {
+			continue
+		}
+
+		result, err := c.getMarketChart(ctx, symbol, id, vsCurrency, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, point := range result.Prices {
+			if time.UnixMilli(int64(point[0])).After(end) {
+				continue
+			}
+			prices[symbol] = point[1]
+		}
+	}
+
+	return prices, nil
+}
+
+// GetHistoricalPricesAt returns the CoinGecko price of the symbol nearest to each of the
+// times, or 0 if there is none within 12 hours or the symbol has no coin ID. CoinGecko
+// returns 5-minute prices for the last day, hourly prices for the last 90 days and daily
+// prices before that.
+func (c *Client) GetHistoricalPricesAt(ctx context.Context, symbol, fiat string, times []time.Time) ([]float64, error) {
+	prices := make([]float64, len(times))
+	id, ok := c.ids[symbol]
+	if !ok || len(times) == 0 {
+		return prices, nil
+	}
+
+	start, end := times[0], times[0]
+	for _, t := range times {
+		if t.Before(start) {
+			start = t
+		}
+		if t.After(end) {
+			end = t
+		}
+	}
+
+	result, err := c.getMarketChart(ctx, symbol, id, strings.ToLower(fiat), start.Add(-priceWindow), end.Add(priceWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range times {
+		var nearest time.Duration
+		for _, point := range result.Prices {
+			distance := time.UnixMilli(int64(point[0])).Sub(t).Abs()
+			if distance > priceWindow || (prices[i] > 0 && distance >= nearest) {
+				continue
+			}
+			prices[i] = point[1]
+			nearest = distance
+		}
+	}
+
+	return prices, nil
+}
+
+// getMarketChart returns the prices of the coin from start to end.
+func (c *Client) getMarketChart(ctx context.Context, symbol, id, vsCurrency string, start, end time.Time) (marketChartResult, error) {
+	endpoint := fmt.Sprintf(marketChartRangePath, c.baseUrl, id, vsCurrency, start.Unix(), end.Unix())
+	result, err := client.Get[marketChartResult](ctx, endpoint, c.header(), c.httpClient)
+	if err != nil {
+		return result, fmt.Errorf("failed to get historical %s prices: %w", symbol, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package coingecko
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetHistoricalPricesAtUsesNearestPrice(t *testing.T) {
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		// Hourly prices from 11:00 to 14:00
+		fmt.Fprintf(w, `{"prices": [[%d, 0.011], [%d, 0.012], [%d, 0.013], [%d, 0.014]]}`,
+			base.Add(-time.Hour).UnixMilli(), base.UnixMilli(), base.Add(time.Hour).UnixMilli(), base.Add(2*time.Hour).UnixMilli())
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{HttpClient: server.Client()})
+	c.baseUrl = server.URL
+
+	times := []time.Time{
+		base.Add(20 * time.Minute),
+		base.Add(40 * time.Minute),
+		base.Add(-3 * time.Hour),
+		base.Add(15 * time.Hour),
+	}
+	prices, err := c.GetHistoricalPricesAt(context.Background(), "POKT", "USD", times)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last time is more than 12 hours from any price
+	want := []float64{0.012, 0.013, 0.011, 0}
+	for i := range want {
+		if prices[i] != want[i] {
+			t.Errorf("price at %s = %v, want %v", times[i].Format(time.TimeOnly), prices[i], want[i])
+		}
+	}
+
+	wantQuery := fmt.Sprintf("vs_currency=usd&from=%d&to=%d", times[2].Add(-priceWindow).Unix(), times[3].Add(priceWindow).Unix())
+	if query != wantQuery {
+		t.Errorf("query = %s, want %s", query, wantQuery)
+	}
+}
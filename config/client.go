@@ -0,0 +1,104 @@
+package config
+
+import (
+	"github.com/commoddity/bank-informer/client"
+)
+
+// ClientConfig returns the HTTP client settings of the config: the connection
+// settings, retry policy and circuit breaker of all upstreams, with the overrides
+// and rate limits of each upstream, and the API keys to redact from cassettes.
+func (c *Config) ClientConfig() client.Config {
+	transport := transportConfig(client.DefaultTransportConfig, c.Transport)
+	policy := retryPolicy(client.DefaultRetryPolicy, c.Retry)
+	breaker := breakerPolicy(client.DefaultBreakerPolicy, c.CircuitBreaker)
+
+	transports := make(map[string]client.TransportConfig)
+	upstreams := make(map[string]client.RetryPolicy)
+	breakers := make(map[string]client.BreakerPolicy)
+	rateLimits := make(map[string]client.RateLimit)
+	for name, upstream := range c.Upstreams {
+		if upstream.Transport != (TransportConfig{}) {
+			transports[name] = transportConfig(transport, upstream.Transport)
+		}
+		upstreams[name] = retryPolicy(policy, upstream.Retry)
+		breakers[name] = breakerPolicy(breaker, upstream.CircuitBreaker)
+		if upstream.RateLimit.RequestsPerSecond > 0 {
+			rateLimits[name] = client.RateLimit{
+				RequestsPerSecond: upstream.RateLimit.RequestsPerSecond,
+				Burst:             upstream.RateLimit.Burst,
+			}
+		}
+	}
+
+	return client.Config{
+		Transport:  transport,
+		Transports: transports,
+		Retry:      policy,
+		Upstreams:  upstreams,
+		RateLimits: rateLimits,
+		Breaker:    breaker,
+		Breakers:   breakers,
+		Secrets:    []string{c.PathApiKey, c.CMCAPIKey, c.CoinGeckoAPIKey},
+	}
+}
+
+// transportConfig returns the base connection settings with the fields set in transport overridden.
+func transportConfig(base client.TransportConfig, transport TransportConfig) client.TransportConfig {
+	if transport.Proxy != "" {
+		base.Proxy = transport.Proxy
+	}
+	if transport.CAFile != "" {
+		base.CAFile = transport.CAFile
+	}
+	if transport.CertFile != "" {
+		base.CertFile = transport.CertFile
+		base.KeyFile = transport.KeyFile
+	}
+	if transport.RequestTimeout > 0 {
+		base.RequestTimeout = transport.RequestTimeout
+	}
+	if transport.DialTimeout > 0 {
+		base.DialTimeout = transport.DialTimeout
+	}
+	if transport.TLSHandshakeTimeout > 0 {
+		base.TLSHandshakeTimeout = transport.TLSHandshakeTimeout
+	}
+	if transport.ResponseHeaderTimeout > 0 {
+		base.ResponseHeaderTimeout = transport.ResponseHeaderTimeout
+	}
+	if transport.IdleConnTimeout > 0 {
+		base.IdleConnTimeout = transport.IdleConnTimeout
+	}
+	if transport.KeepAlive > 0 {
+		base.KeepAlive = transport.KeepAlive
+	}
+	if transport.DisableKeepAlives {
+		base.DisableKeepAlives = true
+	}
+	return base
+}
+
+// retryPolicy returns the base retry policy with the fields set in retry overridden.
+func retryPolicy(base client.RetryPolicy, retry RetryConfig) client.RetryPolicy {
+	if retry.MaxRetries != nil {
+		base.MaxRetries = *retry.MaxRetries
+	}
+	if retry.BaseDelay > 0 {
+		base.BaseDelay = retry.BaseDelay
+	}
+	if retry.MaxDelay > 0 {
+		base.MaxDelay = retry.MaxDelay
+	}
+	return base
+}
+
+// breakerPolicy returns the base circuit breaker policy with the fields set in breaker overridden.
+func breakerPolicy(base client.BreakerPolicy, breaker BreakerConfig) client.BreakerPolicy {
+	if breaker.FailureThreshold > 0 {
+		base.FailureThreshold = breaker.FailureThreshold
+	}
+	if breaker.CoolDown > 0 {
+		base.CoolDown = breaker.CoolDown
+	}
+	return base
+}
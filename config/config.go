@@ -3,9 +3,13 @@ package config
 import (
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
+	"github.com/commoddity/bank-informer/onchain"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,6 +22,22 @@ const (
 	defaultCryptoFiatConversion = "USD"
 	defaultConvertCurrencies    = "USD"
 	defaultCryptoValues         = "USDC,ETH,POKT"
+	defaultBTCEsploraApiUrl     = "https://blockstream.info/api"
+	defaultBTCGapLimit          = 20
+	defaultSolanaRpcUrl         = "https://api.mainnet-beta.solana.com"
+	defaultPriceProvider        = PriceProviderCMC
+	defaultDivergenceThreshold  = 1.0
+	defaultDepegThresholdBps    = 50
+	defaultOnchainMaxQuoteAge   = 25 * time.Hour
+	defaultRunTimeout           = 5 * time.Minute
+
+	PriceProviderCMC       = "cmc"
+	PriceProviderCoinGecko = "coingecko"
+	PriceProviderOnchain   = "onchain"
+
+	FXProviderECB  = "ecb"
+	FXProviderCMC  = "cmc"
+	FXProviderFile = "file"
 )
 
 var (
@@ -33,11 +53,118 @@ type Config struct {
 	PathApiKey           string   `yaml:"path_api_key"`           // required
 	EthWalletAddress     string   `yaml:"eth_wallet_address"`     // required
 	PoktWalletAddress    string   `yaml:"pokt_wallet_address"`    // required
-	CMCAPIKey            string   `yaml:"cmc_api_key"`            // required
+	CMCAPIKey            string   `yaml:"cmc_api_key"`            // required if price_provider is "cmc"
 	PoktExchangeAmount   int64    `yaml:"pokt_exchange_amount"`   // optional
 	CryptoFiatConversion string   `yaml:"crypto_fiat_conversion"` // optional, defaults to "USD"
 	ConvertCurrencies    []string `yaml:"convert_currencies"`     // optional, defaults to "USD"
 	CryptoValues         []string `yaml:"crypto_values"`          // optional, defaults to "USDC,ETH,POKT"
+
+	RunTimeout time.Duration `yaml:"run_timeout"` // optional, defaults to "5m"
+
+	// HTTP
+	Transport      TransportConfig           `yaml:"transport"`       // optional, the connection settings of all upstreams
+	Retry          RetryConfig               `yaml:"retry"`           // optional, the retry policy of all upstreams
+	CircuitBreaker BreakerConfig             `yaml:"circuit_breaker"` // optional, the circuit breaker of all upstreams
+	Upstreams      map[string]UpstreamConfig `yaml:"upstreams"`       // optional, PATH service ID or host to its settings
+
+	// POKT
+	PoktTrackStaking      bool     `yaml:"pokt_track_staking"`       // optional, defaults to false
+	PoktIncomeAddresses   []string `yaml:"pokt_income_addresses"`    // optional
+	PoktIncomeStartHeight int64    `yaml:"pokt_income_start_height"` // optional, defaults to the latest height on the first run
+	MorseAddresses        []string `yaml:"morse_addresses"`          // optional
+
+	// BTC
+	BTCAddresses     []string `yaml:"btc_addresses"`       // optional
+	BTCXPubs         []string `yaml:"btc_xpubs"`           // optional
+	BTCGapLimit      int      `yaml:"btc_gap_limit"`       // optional, defaults to 20
+	BTCEsploraApiUrl string   `yaml:"btc_esplora_api_url"` // optional, defaults to "https://blockstream.info/api"
+
+	// Solana
+	SolanaWalletAddresses []string          `yaml:"solana_wallet_addresses"` // optional
+	SolanaTokenMints      map[string]string `yaml:"solana_token_mints"`      // optional, mint address to symbol
+	SolanaServiceID       string            `yaml:"solana_service_id"`       // optional, sends Solana requests through PATH
+	SolanaRpcUrl          string            `yaml:"solana_rpc_url"`          // optional, defaults to "https://api.mainnet-beta.solana.com"
+
+	// Prices
+	PriceProvider   string            `yaml:"price_provider"`    // optional, "cmc", "coingecko" or "onchain", defaults to "cmc"
+	CoinGeckoAPIKey string            `yaml:"coingecko_api_key"` // optional
+	CoinGeckoPro    bool              `yaml:"coingecko_pro"`     // optional, uses the pro API with coingecko_api_key
+	CoinGeckoIDs    map[string]string `yaml:"coingecko_ids"`     // optional, symbol to CoinGecko coin ID
+	CMCIDs          map[string]string `yaml:"cmc_ids"`           // optional, symbol to CoinMarketCap ID or slug
+
+	CMCMonthlyCreditBudget int `yaml:"cmc_monthly_credit_budget"` // optional, uses cached rates when it would be exceeded
+
+	PriceProviders           []string `yaml:"price_providers"`            // optional, uses the median of two or more providers instead of price_provider
+	PriceDivergenceThreshold float64  `yaml:"price_divergence_threshold"` // optional, percentage, defaults to 1.0
+
+	PriceAlias map[string]string `yaml:"price_alias"` // optional, symbol to the symbol whose price it uses, e.g. WPOKT to POKT
+
+	Stablecoins       map[string]string `yaml:"stablecoins"`         // optional, symbol to peg currency, defaults to USDC and USDT pegged to USD
+	DepegThresholdBps float64           `yaml:"depeg_threshold_bps"` // optional, defaults to 50
+
+	RateCacheMaxAge time.Duration `yaml:"rate_cache_max_age"` // optional, e.g. "30m", disabled by default
+
+	// On-chain prices
+	OnchainFeeds       map[string]string      `yaml:"onchain_feeds"`         // optional, symbol to Chainlink USD aggregator address
+	OnchainPools       map[string]OnchainPool `yaml:"onchain_pools"`         // optional, symbol to the Uniswap pool it is priced from
+	OnchainMaxQuoteAge time.Duration          `yaml:"onchain_max_quote_age"` // optional, defaults to "25h"
+
+	// FX
+	FXProvider string `yaml:"fx_provider"` // optional, "ecb", "cmc" or "file", disabled by default
+	FXFile     string `yaml:"fx_file"`     // required if fx_provider is "file"
+}
+
+// RetryConfig is a retry policy. Unset fields inherit the default retry policy,
+// or for an upstream, the retry policy of all upstreams.
+type RetryConfig struct {
+	MaxRetries *int          `yaml:"max_retries"` // optional, defaults to 3
+	BaseDelay  time.Duration `yaml:"base_delay"`  // optional, defaults to "200ms"
+	MaxDelay   time.Duration `yaml:"max_delay"`   // optional, defaults to "10s"
+}
+
+// UpstreamConfig holds the settings of a single upstream.
+type UpstreamConfig struct {
+	Transport      TransportConfig `yaml:"transport"`       // optional
+	Retry          RetryConfig     `yaml:"retry"`           // optional
+	RateLimit      RateLimitConfig `yaml:"rate_limit"`      // optional
+	CircuitBreaker BreakerConfig   `yaml:"circuit_breaker"` // optional
+}
+
+// TransportConfig holds connection settings. Unset fields inherit the default
+// settings, or for an upstream, the connection settings of all upstreams.
+type TransportConfig struct {
+	Proxy                 string        `yaml:"proxy"`                   // optional, e.g. "http://proxy:3128" or "socks5://localhost:1080"
+	CAFile                string        `yaml:"ca_file"`                 // optional, PEM bundle of extra CA certificates
+	CertFile              string        `yaml:"cert_file"`               // optional, PEM client certificate for mTLS
+	KeyFile               string        `yaml:"key_file"`                // required with cert_file
+	RequestTimeout        time.Duration `yaml:"request_timeout"`         // optional, per attempt including the body, defaults to "10s"
+	DialTimeout           time.Duration `yaml:"dial_timeout"`            // optional, defaults to "30s"
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`   // optional, defaults to "10s"
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"` // optional, defaults to "10s"
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`       // optional, defaults to "90s"
+	KeepAlive             time.Duration `yaml:"keep_alive"`              // optional, TCP keep-alive interval, defaults to "30s"
+	DisableKeepAlives     bool          `yaml:"disable_keep_alives"`     // optional, defaults to false
+}
+
+// BreakerConfig is a circuit breaker policy. Unset fields inherit the default
+// policy, or for an upstream, the circuit breaker of all upstreams.
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // optional, defaults to 3
+	CoolDown         time.Duration `yaml:"cool_down"`         // optional, defaults to "30m"
+}
+
+// RateLimitConfig is the token bucket rate limit of an upstream.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"` // required to enable the rate limit
+	Burst             int     `yaml:"burst"`               // optional, defaults to 1
+}
+
+// OnchainPool is a Uniswap V2 or V3 pool that prices a token in its quote symbol.
+type OnchainPool struct {
+	Address string `yaml:"address"` // required
+	Version string `yaml:"version"` // optional, "v2" or "v3", defaults to "v2"
+	Token   string `yaml:"token"`   // required, address of the token to price
+	Quote   string `yaml:"quote"`   // required, "USD" or a symbol with a Chainlink feed
 }
 
 // LoadConfig loads the Bank Informer configuration from a YAML file,
@@ -68,6 +195,54 @@ func LoadConfig() (*Config, error) {
 	return &config, nil
 }
 
+// UsesCMC reports whether CoinMarketCap is used as a price or FX provider.
+func (c *Config) UsesCMC() bool {
+	return slices.Contains(c.PriceProviders, PriceProviderCMC) || c.FXProvider == FXProviderCMC
+}
+
+func (c *Config) retryConfigs() []RetryConfig {
+	retries := []RetryConfig{c.Retry}
+	for _, upstream := range c.Upstreams {
+		retries = append(retries, upstream.Retry)
+	}
+	return retries
+}
+
+func (c *Config) transportConfigs() []TransportConfig {
+	transports := []TransportConfig{c.Transport}
+	for _, upstream := range c.Upstreams {
+		transports = append(transports, upstream.Transport)
+	}
+	return transports
+}
+
+func (t TransportConfig) validate() error {
+	if t.Proxy != "" {
+		proxyURL, err := url.Parse(t.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy %s: %w", t.Proxy, err)
+		}
+		if !slices.Contains([]string{"http", "https", "socks5", "socks5h"}, proxyURL.Scheme) {
+			return fmt.Errorf("invalid proxy %s: scheme must be http, https, socks5 or socks5h", t.Proxy)
+		}
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("invalid transport: cert_file and key_file must be set together")
+	}
+	if t.RequestTimeout < 0 || t.DialTimeout < 0 || t.TLSHandshakeTimeout < 0 || t.ResponseHeaderTimeout < 0 || t.IdleConnTimeout < 0 || t.KeepAlive < 0 {
+		return fmt.Errorf("invalid transport: timeouts and keep_alive must not be negative")
+	}
+	return nil
+}
+
+func (c *Config) breakerConfigs() []BreakerConfig {
+	breakers := []BreakerConfig{c.CircuitBreaker}
+	for _, upstream := range c.Upstreams {
+		breakers = append(breakers, upstream.CircuitBreaker)
+	}
+	return breakers
+}
+
 // validateAndSetDefaults checks that all required fields are provided,
 // and assigns default values to any missing optional fields.
 func (c *Config) validateAndSetDefaults() error {
@@ -80,8 +255,72 @@ func (c *Config) validateAndSetDefaults() error {
 	if c.PoktWalletAddress == "" {
 		return fmt.Errorf("missing required field: pokt_wallet_address")
 	}
-	if c.CMCAPIKey == "" {
-		return fmt.Errorf("missing required field: cmc_api_key")
+	if c.PriceProvider == "" {
+		c.PriceProvider = defaultPriceProvider
+	}
+	if len(c.PriceProviders) == 0 {
+		c.PriceProviders = []string{c.PriceProvider}
+	}
+	for _, provider := range c.PriceProviders {
+		switch provider {
+		case PriceProviderCMC:
+			if c.CMCAPIKey == "" {
+				return fmt.Errorf("missing required field: cmc_api_key")
+			}
+		case PriceProviderCoinGecko:
+			if c.CoinGeckoPro && c.CoinGeckoAPIKey == "" {
+				return fmt.Errorf("missing required field: coingecko_api_key")
+			}
+		case PriceProviderOnchain:
+		default:
+			return fmt.Errorf("invalid price provider: %s", provider)
+		}
+	}
+	switch c.FXProvider {
+	case "", FXProviderECB:
+	case FXProviderCMC:
+		if c.CMCAPIKey == "" {
+			return fmt.Errorf("missing required field: cmc_api_key")
+		}
+	case FXProviderFile:
+		if c.FXFile == "" {
+			return fmt.Errorf("missing required field: fx_file")
+		}
+	default:
+		return fmt.Errorf("invalid fx_provider: %s", c.FXProvider)
+	}
+	for alias, canonical := range c.PriceAlias {
+		if _, ok := c.PriceAlias[canonical]; ok {
+			return fmt.Errorf("invalid price_alias: %s is aliased to %s, which is itself an alias", alias, canonical)
+		}
+	}
+	for symbol, pool := range c.OnchainPools {
+		if pool.Address == "" || pool.Token == "" || pool.Quote == "" {
+			return fmt.Errorf("missing required field in onchain_pools %s: address, token and quote are required", symbol)
+		}
+		switch pool.Version {
+		case "":
+			pool.Version = onchain.PoolVersionV2
+			c.OnchainPools[symbol] = pool
+		case onchain.PoolVersionV2, onchain.PoolVersionV3:
+		default:
+			return fmt.Errorf("invalid version in onchain_pools %s: %s", symbol, pool.Version)
+		}
+	}
+	if c.OnchainMaxQuoteAge == 0 {
+		c.OnchainMaxQuoteAge = defaultOnchainMaxQuoteAge
+	}
+	if c.CMCMonthlyCreditBudget < 0 {
+		return fmt.Errorf("invalid cmc_monthly_credit_budget: %d", c.CMCMonthlyCreditBudget)
+	}
+	if c.PriceDivergenceThreshold == 0 {
+		c.PriceDivergenceThreshold = defaultDivergenceThreshold
+	}
+	if c.Stablecoins == nil {
+		c.Stablecoins = map[string]string{"USDC": "USD", "USDT": "USD"}
+	}
+	if c.DepegThresholdBps == 0 {
+		c.DepegThresholdBps = defaultDepegThresholdBps
 	}
 	if c.CryptoFiatConversion == "" {
 		c.CryptoFiatConversion = defaultCryptoFiatConversion
@@ -92,5 +331,46 @@ func (c *Config) validateAndSetDefaults() error {
 	if len(c.CryptoValues) == 0 {
 		c.CryptoValues = []string{defaultCryptoValues}
 	}
+	for _, retry := range c.retryConfigs() {
+		if retry.MaxRetries != nil && *retry.MaxRetries < 0 {
+			return fmt.Errorf("invalid max_retries: %d", *retry.MaxRetries)
+		}
+		if retry.BaseDelay < 0 || retry.MaxDelay < 0 {
+			return fmt.Errorf("invalid retry delay: base_delay and max_delay must not be negative")
+		}
+	}
+	for _, transport := range c.transportConfigs() {
+		if err := transport.validate(); err != nil {
+			return err
+		}
+	}
+	for _, breaker := range c.breakerConfigs() {
+		if breaker.FailureThreshold < 0 || breaker.CoolDown < 0 {
+			return fmt.Errorf("invalid circuit_breaker: failure_threshold and cool_down must not be negative")
+		}
+	}
+	for name, upstream := range c.Upstreams {
+		if upstream.RateLimit.RequestsPerSecond < 0 || upstream.RateLimit.Burst < 0 {
+			return fmt.Errorf("invalid rate_limit for upstream %s: requests_per_second and burst must not be negative", name)
+		}
+	}
+	if c.PoktIncomeStartHeight < 0 {
+		return fmt.Errorf("invalid pokt_income_start_height: %d", c.PoktIncomeStartHeight)
+	}
+	if c.RunTimeout == 0 {
+		c.RunTimeout = defaultRunTimeout
+	}
+	if c.BTCGapLimit < 0 {
+		return fmt.Errorf("invalid btc_gap_limit: %d", c.BTCGapLimit)
+	}
+	if c.BTCGapLimit == 0 {
+		c.BTCGapLimit = defaultBTCGapLimit
+	}
+	if c.BTCEsploraApiUrl == "" {
+		c.BTCEsploraApiUrl = defaultBTCEsploraApiUrl
+	}
+	if c.SolanaRpcUrl == "" {
+		c.SolanaRpcUrl = defaultSolanaRpcUrl
+	}
 	return nil
 }
@@ -4,26 +4,39 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/commoddity/bank-informer/config"
 	"github.com/commoddity/bank-informer/persistence"
 )
 
+var headers = []string{"date", "cryptoSymbol", "cryptoBalance", "fiatValue", "fiatBalance", "backfilled"}
+
+// Record is a row of the CSV file.
+type Record struct {
+	Date          string
+	Crypto        string
+	CryptoBalance float64
+	FiatValue     float64
+	FiatBalance   float64
+	Backfilled    bool
+}
+
 func WriteCryptoValuesToCSV(p *persistence.Persistence, cryptos []string) error {
-	currentDate := time.Now().Format("2006-01-02")
+	return WriteCryptoValuesToCSVForDate(p, cryptos, time.Now().Format("2006-01-02"))
+}
 
-	// Read existing records
-	records, err := readCSV(config.CSVPath)
-	if err != nil && !os.IsNotExist(err) {
+// WriteCryptoValuesToCSVForDate writes the average crypto values stored for the
+// given date (YYYY-MM-DD) to the CSV file, along with a total row for the date.
+func WriteCryptoValuesToCSVForDate(p *persistence.Persistence, cryptos []string, currentDate string) error {
+	// Read existing records, without the headers
+	records, err := readRecords(config.CSVPath)
+	if err != nil {
 		return err
 	}
 
-	// Check if the file has headers
-	hasHeaders := len(records) > 0 && records[0][0] == "date"
-
 	updated := false
-	totalFiatBalance := 0.0
 	for _, crypto := range cryptos {
 		key := fmt.Sprintf("%s-%s", crypto, currentDate)
 		avgValues, err := p.GetAverageCryptoValues(key)
@@ -37,13 +50,27 @@ func WriteCryptoValuesToCSV(p *persistence.Persistence, cryptos []string) error
 			fmt.Sprintf("%f", avgValues.CryptoBalance),
 			fmt.Sprintf("%f", avgValues.FiatValue),
 			fmt.Sprintf("%f", avgValues.FiatBalance),
+			strconv.FormatBool(avgValues.Backfilled),
 		}
 
-		totalFiatBalance += avgValues.FiatBalance
-
 		_, records = updateOrAddRecord(records, record)
 	}
 
+	// Total the date's rows, including rows no longer stored in persistence
+	totalFiatBalance := 0.0
+	totalBackfilled := false
+	for _, record := range records {
+		if record[0] != currentDate || record[1] == "TOTAL" {
+			continue
+		}
+		fiatBalance, err := strconv.ParseFloat(record[4], 64)
+		if err != nil {
+			continue
+		}
+		totalFiatBalance += fiatBalance
+		totalBackfilled = totalBackfilled || record[5] == "true"
+	}
+
 	// Add total row
 	totalRow := []string{
 		currentDate,
@@ -51,17 +78,81 @@ func WriteCryptoValuesToCSV(p *persistence.Persistence, cryptos []string) error
 		"",
 		"",
 		fmt.Sprintf("%f", totalFiatBalance),
+		strconv.FormatBool(totalBackfilled),
 	}
 	updated, records = updateOrAddRecord(records, totalRow)
 
 	// Rewrite the CSV file only if updated
 	if updated {
-		return writeCSV(config.CSVPath, records, !hasHeaders)
+		return writeCSV(config.CSVPath, records)
 	}
 
 	return nil
 }
 
+// HasRecord reports whether the CSV file has a row for the crypto on the given date (YYYY-MM-DD).
+func HasRecord(date, crypto string) (bool, error) {
+	records, err := readRecords(config.CSVPath)
+	if err != nil {
+		return false, err
+	}
+	for _, record := range records {
+		if record[0] == date && record[1] == crypto {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReadRecords returns the rows of the CSV file, excluding total rows.
+func ReadRecords() ([]Record, error) {
+	records, err := readRecords(config.CSVPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed []Record
+	for _, record := range records {
+		if record[1] == "TOTAL" {
+			continue
+		}
+		cryptoBalance, _ := strconv.ParseFloat(record[2], 64)
+		fiatValue, _ := strconv.ParseFloat(record[3], 64)
+		fiatBalance, _ := strconv.ParseFloat(record[4], 64)
+		parsed = append(parsed, Record{
+			Date:          record[0],
+			Crypto:        record[1],
+			CryptoBalance: cryptoBalance,
+			FiatValue:     fiatValue,
+			FiatBalance:   fiatBalance,
+			Backfilled:    record[5] == "true",
+		})
+	}
+	return parsed, nil
+}
+
+// readRecords reads the CSV file without its headers. Rows written before a
+// column was added are padded with empty values.
+func readRecords(filePath string) ([][]string, error) {
+	records, err := readCSV(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// Check if the file has headers
+	if len(records) > 0 && records[0][0] == "date" {
+		records = records[1:]
+	}
+
+	for i, record := range records {
+		for len(record) < len(headers) {
+			record = append(record, "")
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
 func readCSV(filePath string) ([][]string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -73,6 +164,8 @@ func readCSV(filePath string) ([][]string, error) {
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	// Allow rows written before the backfilled column was added
+	reader.FieldsPerRecord = -1
 	return reader.ReadAll()
 }
 
@@ -92,7 +185,7 @@ func updateOrAddRecord(records [][]string, newRecord []string) (bool, [][]string
 	return updated, records
 }
 
-func writeCSV(filePath string, records [][]string, writeHeaders bool) error {
+func writeCSV(filePath string, records [][]string) error {
 	file, err := os.Create(filePath)
 	if err != nil {
 		return err
@@ -101,12 +194,8 @@ func writeCSV(filePath string, records [][]string, writeHeaders bool) error {
 
 	writer := csv.NewWriter(file)
 
-	// Write headers if needed
-	if writeHeaders {
-		headers := []string{"date", "cryptoSymbol", "cryptoBalance", "fiatValue", "fiatBalance"}
-		if err := writer.Write(headers); err != nil {
-			return err
-		}
+	if err := writer.Write(headers); err != nil {
+		return err
 	}
 
 	return writer.WriteAll(records)
@@ -1,9 +1,11 @@
 package eth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -118,6 +120,7 @@ type Client struct {
 	progressChan chan string
 	mutex        *sync.Mutex
 	waitGroup    *sync.WaitGroup
+	blockHeight  int64
 }
 
 func NewClient(config Config, progressChan chan string, mutex *sync.Mutex, waitGroup *sync.WaitGroup) *Client {
@@ -132,6 +135,11 @@ func NewClient(config Config, progressChan chan string, mutex *sync.Mutex, waitG
 	}
 }
 
+// BlockHeight returns the latest block height when the wallet balances were last fetched.
+func (c *Client) BlockHeight() int64 {
+	return c.blockHeight
+}
+
 func ValidateETHWalletAddress(address string) error {
 	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
 		return fmt.Errorf("invalid Ethereum wallet address: %s", address)
@@ -139,13 +147,20 @@ func ValidateETHWalletAddress(address string) error {
 	return nil
 }
 
-func (c *Client) GetETHWalletBalances(balances map[string]float64) error {
+func (c *Client) GetETHWalletBalances(ctx context.Context, balances map[string]float64) error {
 	// Prepare batch request for all tokens
 	var batchRequest []JsonRPCRequest
 	tokenIDMap := make(map[int]string)
 	idCounter := 1
 
+	// Sort the tokens so that requests are reproducible
+	tokens := make([]string, 0, len(balances))
 	for token := range balances {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	for _, token := range tokens {
 		if _, ok := erc20TokenConfig[token]; !ok {
 			continue
 		}
@@ -164,14 +179,25 @@ func (c *Client) GetETHWalletBalances(balances map[string]float64) error {
 		return nil
 	}
 
+	// Get the block height in the same batch, to store with the balances
+	blockNumberID := idCounter
+	batchRequest = append(batchRequest, JsonRPCRequest{Jsonrpc: "2.0", Method: "eth_blockNumber", Params: json.RawMessage(`[]`), Id: blockNumberID})
+
 	// Execute batch request
-	batchResponse, err := c.executeBatchRequest(batchRequest)
+	batchResponse, err := c.executeBatchRequest(ctx, batchRequest)
 	if err != nil {
 		return err
 	}
 
 	// Process responses and update balances
 	for _, response := range batchResponse {
+		if response.Id == blockNumberID && response.Error == nil {
+			if height, err := strconv.ParseInt(strings.TrimPrefix(response.Result, "0x"), 16, 64); err == nil {
+				c.blockHeight = height
+			}
+			continue
+		}
+
 		token, exists := tokenIDMap[response.Id]
 		if !exists {
 			continue
@@ -196,32 +222,26 @@ func (c *Client) GetETHWalletBalances(balances map[string]float64) error {
 	return nil
 }
 
-func (c *Client) executeBatchRequest(batchRequest []JsonRPCRequest) (JsonRPCBatchResponse, error) {
-	const maxRetries = 5
-	var lastErr error
-
+func (c *Client) executeBatchRequest(ctx context.Context, batchRequest []JsonRPCRequest) (JsonRPCBatchResponse, error) {
 	header := http.Header{
 		"Content-Type":      []string{"application/json"},
 		"Target-Service-Id": []string{"eth"},
 		"Authorization":     []string{c.pathAPIKey},
 	}
+	// Balance and contract calls are read-only, so they are safe to retry
+	client.MarkIdempotent(header)
 
 	jsonData, err := json.Marshal(batchRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
 	}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		resp, err := client.Post[JsonRPCBatchResponse](c.url, header, jsonData, c.httpClient)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-
-		return resp, nil
+	resp, err := client.Post[JsonRPCBatchResponse](ctx, c.url, header, jsonData, c.httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute batch request: %w", err)
 	}
 
-	return nil, fmt.Errorf("failed to execute batch request after %d attempts: %w", maxRetries, lastErr)
+	return resp, nil
 }
 
 func (c *Client) getRoundValueForToken(token string) float64 {
@@ -249,3 +269,52 @@ func (c *Client) decodeHexToFloat64(hexValue string) (float64, error) {
 
 	return float64(value), nil
 }
+
+// EthCall is a read-only contract call with ABI-encoded call data.
+type EthCall struct {
+	To   string
+	Data string
+}
+
+// BatchEthCall executes the contract calls at the latest block in a single batch
+// request, and returns the hex-encoded result of each call in the same order.
+func (c *Client) BatchEthCall(ctx context.Context, calls []EthCall) ([]string, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	batchRequest := make([]JsonRPCRequest, len(calls))
+	for i, call := range calls {
+		batchRequest[i] = JsonRPCRequest{
+			Jsonrpc: "2.0",
+			Method:  "eth_call",
+			Params:  json.RawMessage(fmt.Sprintf(`[{"to": "%s", "data": "%s"}, "latest"]`, call.To, call.Data)),
+			Id:      i + 1,
+		}
+	}
+
+	batchResponse, err := c.executeBatchRequest(ctx, batchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Batch responses may be returned in any order
+	results := make([]string, len(calls))
+	for _, response := range batchResponse {
+		if response.Id < 1 || response.Id > len(calls) {
+			continue
+		}
+		call := calls[response.Id-1]
+		if response.Error != nil {
+			return nil, fmt.Errorf("error for call to %s: %s", call.To, response.Error.Message)
+		}
+		results[response.Id-1] = response.Result
+	}
+	for i, result := range results {
+		if result == "" {
+			return nil, fmt.Errorf("no result for call to %s", calls[i].To)
+		}
+	}
+
+	return results, nil
+}
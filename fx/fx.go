@@ -0,0 +1,211 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/commoddity/bank-informer/client"
+	"github.com/commoddity/bank-informer/cmc"
+	"github.com/commoddity/bank-informer/config"
+)
+
+const (
+	ecbURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	cmcURL = "https://pro-api.coinmarketcap.com/v1/tools/price-conversion?amount=1&symbol=%s&convert=%s"
+)
+
+// Provider fetches fiat-to-fiat exchange rates.
+type Provider interface {
+	// Name returns the name of the FX provider, e.g. "ecb".
+	Name() string
+	// GetRates returns the value of one unit of base in each of the quote currencies.
+	GetRates(ctx context.Context, base string, quotes []string) (map[string]float64, error)
+}
+
+/* ------------ ECB Reference Rates ------------ */
+
+// ECB is an FX provider using the European Central Bank's daily reference rates,
+// which are all fetched in a single request.
+type ECB struct {
+	httpClient *http.Client
+}
+
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func NewECB(httpClient *http.Client) *ECB {
+	return &ECB{httpClient: httpClient}
+}
+
+func (e *ECB) Name() string {
+	return config.FXProviderECB
+}
+
+func (e *ECB) GetRates(ctx context.Context, base string, quotes []string) (map[string]float64, error) {
+	envelope, err := client.GetXML[ecbEnvelope](ctx, ecbURL, http.Header{}, e.httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ECB reference rates: %w", err)
+	}
+
+	// ECB reference rates are the value of one euro in each currency
+	eurRates := map[string]float64{"EUR": 1}
+	for _, rate := range envelope.Cube.Cube.Rates {
+		value, err := strconv.ParseFloat(rate.Rate, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ECB rate for %s: %s", rate.Currency, rate.Rate)
+		}
+		eurRates[rate.Currency] = value
+	}
+
+	return crossRates(eurRates, base, quotes)
+}
+
+/* ------------ CoinMarketCap Price Conversion ------------ */
+
+// CMC is an FX provider using the CoinMarketCap price conversion endpoint.
+// The free plan only allows one convert currency per request.
+type CMC struct {
+	apiKey     string
+	credits    cmc.CreditStore
+	httpClient *http.Client
+}
+
+type cmcConversionResult struct {
+	Status cmc.Status `json:"status"`
+	Data   struct {
+		Symbol string `json:"symbol"`
+		Quote  map[string]struct {
+			Price float64 `json:"price"`
+		} `json:"quote"`
+	} `json:"data"`
+}
+
+// NewCMC creates a CoinMarketCap FX provider. If credits is not nil, the
+// credits used by each call are recorded with it.
+func NewCMC(apiKey string, credits cmc.CreditStore, httpClient *http.Client) *CMC {
+	return &CMC{apiKey: apiKey, credits: credits, httpClient: httpClient}
+}
+
+func (c *CMC) Name() string {
+	return config.FXProviderCMC
+}
+
+func (c *CMC) GetRates(ctx context.Context, base string, quotes []string) (map[string]float64, error) {
+	header := http.Header{}
+	header.Set("Accepts", "application/json")
+	header.Add("X-CMC_PRO_API_KEY", c.apiKey)
+
+	rates := map[string]float64{base: 1}
+	for _, quote := range quotes {
+		if quote == base {
+			continue
+		}
+
+		result, err := client.Get[cmcConversionResult](ctx, fmt.Sprintf(cmcURL, base, quote), header, c.httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CoinMarketCap %s/%s conversion: %w", base, quote, err)
+		}
+		if err := cmc.RecordCredits(c.credits, "price-conversion", result.Status); err != nil {
+			return nil, err
+		}
+
+		price, ok := result.Data.Quote[quote]
+		if !ok {
+			return nil, fmt.Errorf("no CoinMarketCap %s/%s conversion found", base, quote)
+		}
+		rates[quote] = price.Price
+	}
+
+	return rates, nil
+}
+
+// EstimateCredits returns the credits used by GetRates, which is one credit per quote currency.
+func (c *CMC) EstimateCredits(base string, quotes []string) int {
+	var credits int
+	for _, quote := range quotes {
+		if quote != base {
+			credits++
+		}
+	}
+	return credits
+}
+
+/* ------------ Local File ------------ */
+
+// File is an FX provider reading rates from a local YAML or JSON file of the form:
+//
+//	base: EUR
+//	rates:
+//	  USD: 1.0842
+//	  GBP: 0.8561
+type File struct {
+	path string
+}
+
+type fileRates struct {
+	Base  string             `yaml:"base"`
+	Rates map[string]float64 `yaml:"rates"`
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Name() string {
+	return config.FXProviderFile
+}
+
+func (f *File) GetRates(ctx context.Context, base string, quotes []string) (map[string]float64, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FX file: %w", err)
+	}
+
+	var file fileRates
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse FX file: %w", err)
+	}
+	if file.Base == "" {
+		return nil, fmt.Errorf("missing base currency in FX file %s", f.path)
+	}
+
+	rates := map[string]float64{file.Base: 1}
+	for currency, rate := range file.Rates {
+		rates[currency] = rate
+	}
+
+	return crossRates(rates, base, quotes)
+}
+
+// crossRates converts rates relative to a reference currency into rates relative to base.
+func crossRates(referenceRates map[string]float64, base string, quotes []string) (map[string]float64, error) {
+	baseRate, ok := referenceRates[base]
+	if !ok || baseRate == 0 {
+		return nil, fmt.Errorf("no FX rate found for %s", base)
+	}
+
+	rates := make(map[string]float64)
+	for _, quote := range quotes {
+		quoteRate, ok := referenceRates[quote]
+		if !ok {
+			return nil, fmt.Errorf("no FX rate found for %s", quote)
+		}
+		rates[quote] = quoteRate / baseRate
+	}
+
+	return rates, nil
+}
@@ -5,6 +5,7 @@ go 1.21.0
 require (
 	github.com/cheggaaa/pb/v3 v3.1.5
 	github.com/dgraph-io/badger/v3 v3.2103.5
+	golang.org/x/crypto v0.14.0
 	golang.org/x/text v0.13.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,17 @@
+package log
+
+import "fmt"
+
+// LogCreditUsage logs the API credits used by a provider today and this month,
+// along with the monthly budget if one is set.
+func (l *Logger) LogCreditUsage(provider string, today, month, budget int) {
+	if budget > 0 {
+		color := colorReset
+		if month >= budget {
+			color = colorRed
+		}
+		fmt.Printf("💳 %s credits: %d today, %s%d of %d this month%s\n", provider, today, color, month, budget, colorReset)
+		return
+	}
+	fmt.Printf("💳 %s credits: %d today, %d this month\n", provider, today, month)
+}
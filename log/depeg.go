@@ -0,0 +1,48 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/commoddity/bank-informer/persistence"
+)
+
+// printPeg prints the deviation of a stablecoin from its peg currency, and warns
+// and stores a depeg event if the deviation is over the threshold. Nothing is
+// printed for other assets, or if there is no exchange rate in the peg currency.
+func (l *Logger) printPeg(name string, exchangeRates map[string]map[string]float64) {
+	peg, ok := l.stablecoins[name]
+	if !ok {
+		return
+	}
+	price, ok := exchangeRates[peg][name]
+	if !ok || price == 0 {
+		return
+	}
+
+	deviationBps := (price - 1) * 10000
+
+	if math.Abs(deviationBps) <= l.depegThresholdBps {
+		fmt.Printf("  %s peg: %+.1f bps from %s1.00%s\n", name, deviationBps, fiatSymbols[peg], colorReset)
+		return
+	}
+
+	fmt.Printf("  %s🚨 %s depeg: %+.1f bps from %s1.00 (over %.1f bps)%s\n",
+		colorRed, name, deviationBps, fiatSymbols[peg], l.depegThresholdBps, colorReset)
+
+	// Cached rates were already recorded when they were fetched
+	if !l.ratesCachedAt.IsZero() {
+		return
+	}
+	err := l.persistence.WriteDepegEvent(persistence.DepegEvent{
+		Symbol:       name,
+		Peg:          peg,
+		Time:         time.Now(),
+		Price:        price,
+		DeviationBps: deviationBps,
+	})
+	if err != nil {
+		fmt.Printf("Error writing depeg event to database: %s\n", err)
+	}
+}
@@ -0,0 +1,105 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/commoddity/bank-informer/persistence"
+	"github.com/commoddity/bank-informer/pokt"
+	"github.com/commoddity/bank-informer/price"
+)
+
+// LogIncome stores each new POKT reward event with its fiat value at the time it
+// was received, then logs the POKT income for the current day, month and year.
+// Events are valued at the historical POKT price nearest their block time. If
+// historical is nil or has no price near an event, the event is valued at the
+// current POKT price and marked as estimated.
+func (l *Logger) LogIncome(ctx context.Context, events []pokt.RewardEvent, exchangeRates map[string]map[string]float64, historical price.HistoricalProvider) {
+	historicalPrices := make([]float64, len(events))
+	if historical != nil && len(events) > 0 {
+		times := make([]time.Time, len(events))
+		for i, event := range events {
+			times[i] = event.Time
+		}
+		prices, err := historical.GetHistoricalPricesAt(ctx, "POKT", l.cryptoFiatConversion, times)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to get historical POKT prices, valuing new income at the current price: %s\n", err)
+		} else {
+			historicalPrices = prices
+		}
+	}
+
+	for i, event := range events {
+		fiatValue := historicalPrices[i]
+		estimated := fiatValue <= 0
+		if estimated {
+			fiatValue = exchangeRates[l.cryptoFiatConversion]["POKT"]
+		}
+
+		err := l.persistence.WriteIncomeEvent(persistence.IncomeEvent{
+			Address:    event.Address,
+			Kind:       event.Kind,
+			Height:     event.Height,
+			Time:       event.Time,
+			Amount:     event.Amount,
+			Fiat:       l.cryptoFiatConversion,
+			FiatValue:  fiatValue,
+			FiatAmount: event.Amount * fiatValue,
+			Estimated:  estimated,
+		})
+		if err != nil {
+			fmt.Printf("Error writing income event to database: %s\n", err)
+		}
+	}
+
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	startOfYear := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.Local)
+
+	incomeEvents, err := l.persistence.GetIncomeEvents(startOfYear)
+	if err != nil {
+		fmt.Printf("Error reading income events from database: %s\n", err)
+		return
+	}
+
+	periods := []struct {
+		name  string
+		start time.Time
+	}{
+		{"Today", startOfDay},
+		{"This Month", startOfMonth},
+		{"This Year", startOfYear},
+	}
+
+	fmt.Println("\n<--------- 🪙 POKT Income 🪙 --------->")
+	var hasEstimates bool
+	for _, period := range periods {
+		var amount, fiatAmount float64
+		var estimated bool
+		for _, event := range incomeEvents {
+			// Only sum income valued in the current fiat conversion currency
+			if event.Time.Before(period.start) || event.Fiat != l.cryptoFiatConversion {
+				continue
+			}
+			amount += event.Amount
+			fiatAmount += event.FiatAmount
+			estimated = estimated || event.Estimated
+		}
+
+		marker := ""
+		if estimated {
+			marker = " *"
+			hasEstimates = true
+		}
+		fmt.Printf("%-10s - %s POKT = %s%s %s%s\n",
+			period.name,
+			formatCryptoFloat("POKT", amount),
+			fiatSymbols[l.cryptoFiatConversion], formatFiatFloat("", fiatAmount),
+			l.cryptoFiatConversion, marker)
+	}
+	if hasEstimates {
+		fmt.Printf("  %s⚠️  * includes income estimated at the POKT price when it was found, as no price was found for when it was received%s\n", colorYellow, colorReset)
+	}
+}
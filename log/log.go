@@ -18,37 +18,68 @@ type Logger struct {
 	cryptoFiatConversion string
 	cryptoValues         []string
 	convertCurrencies    []string
-	poktExchangeAmount   int64
 	persistence          *persistence.Persistence
 	progressChan         chan string
 	chanLength           int
+	notes                map[string][]string
+	ratesCachedAt        time.Time
+	assets               map[string]persistence.CryptoValues
+	stablecoins          map[string]string
+	depegThresholdBps    float64
 }
 
 type Config struct {
 	CryptoFiatConversion string
 	CryptoValues         []string
 	ConvertCurrencies    []string
-	PoktExchangeAmount   int64
+	// Stablecoins maps stablecoin symbols to their peg currency
+	Stablecoins       map[string]string
+	DepegThresholdBps float64
 }
 
-// Modified New function to include Persistence and POKT exchange amount
+// Modified New function to include Persistence
 func New(config Config, persistence *persistence.Persistence, progressChan chan string, chanLength int) *Logger {
 	return &Logger{
 		cryptoFiatConversion: config.CryptoFiatConversion,
 		cryptoValues:         config.CryptoValues,
 		convertCurrencies:    config.ConvertCurrencies,
-		poktExchangeAmount:   config.PoktExchangeAmount,
 		persistence:          persistence,
 		progressChan:         progressChan,
 		chanLength:           chanLength,
+		notes:                make(map[string][]string),
+		stablecoins:          config.Stablecoins,
+		depegThresholdBps:    config.DepegThresholdBps,
+	}
+}
+
+// Flag adds a note to be displayed below the balance row with the given name.
+func (l *Logger) Flag(name, note string) {
+	l.notes[name] = append(l.notes[name], note)
+}
+
+// SetRatesCachedAt labels the displayed values as priced at exchange rates cached at t.
+func (l *Logger) SetRatesCachedAt(t time.Time) {
+	l.ratesCachedAt = t
+}
+
+// Assets returns the values of each balance and position logged by LogBalances,
+// keyed by symbol or position key, for the snapshot of the run.
+func (l *Logger) Assets() map[string]persistence.CryptoValues {
+	return l.assets
+}
+
+func (l *Logger) printNotes(name string) {
+	for _, note := range l.notes[name] {
+		fmt.Printf("  %s⚠️  %s%s\n", colorYellow, note, colorReset)
 	}
 }
 
 const (
-	colorRed   = "\033[31m"
-	colorGreen = "\033[32m"
-	colorBlue  = "\033[34m"
-	colorReset = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorBlue   = "\033[34m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
 )
 
 var (
@@ -113,6 +144,8 @@ var (
 		"USDT":  2,
 		"ETH":   6,
 		"WBTC":  6,
+		"BTC":   6,
+		"SOL":   4,
 	}
 
 	fiatRoundValues = map[string]int{
@@ -157,6 +190,17 @@ func (l *Logger) RunProgressBar() {
 	}
 }
 
+// Position is an amount held outside of the wallet balances, such as tokens
+// on an exchange or staked tokens. It is valued at the exchange rate of Symbol
+// and stored in persistence under Key.
+type Position struct {
+	Section string
+	Name    string
+	Key     string
+	Symbol  string
+	Amount  float64
+}
+
 type cryptoBalance struct {
 	name        string
 	balance     float64
@@ -164,11 +208,11 @@ type cryptoBalance struct {
 	fiatBalance float64
 }
 
-func (l *Logger) LogBalances(balances map[string]float64, fiatValues map[string]float64, exchangeRates map[string]map[string]float64) {
-	currentDate := time.Now().Format("2006-01-02") // format: YYYY-MM-DD
+func (l *Logger) LogBalances(balances map[string]float64, positions []Position, fiatValues map[string]float64, exchangeRates map[string]map[string]float64) {
 	previousDate := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
 
 	fiatTotal := 0.0
+	l.assets = make(map[string]persistence.CryptoValues)
 
 	<-time.After(100 * time.Millisecond)
 
@@ -202,7 +246,13 @@ func (l *Logger) LogBalances(balances map[string]float64, fiatValues map[string]
 	})
 
 	// Calculate alignment widths for proper formatting
-	cryptoWidth, balanceWidth, fiatValueWidth, fiatBalanceWidth := l.calculateAlignmentWidths(balances, exchangeRates)
+	cryptoWidth, balanceWidth, fiatValueWidth, fiatBalanceWidth := l.calculateAlignmentWidths(balances, positions, exchangeRates)
+
+	if !l.ratesCachedAt.IsZero() {
+		age := time.Since(l.ratesCachedAt).Round(time.Minute)
+		fmt.Printf("\n%s💾 Using exchange rates cached at %s (%s old)%s\n",
+			colorYellow, l.ratesCachedAt.Format("2006-01-02 15:04:05"), age, colorReset)
+	}
 
 	fmt.Println("\n<--------- 🔐 Crypto Balances 🔐 --------->")
 	for _, cb := range cryptoBalances {
@@ -231,22 +281,19 @@ func (l *Logger) LogBalances(balances map[string]float64, fiatValues map[string]
 			fiatTotal += avgValues.FiatBalance
 		}
 
+		l.printNotes(cb.name)
+		l.printPeg(cb.name, exchangeRates)
+
 		if cb.name == "POKT" || cb.name == "WPOKT" {
 			poktTotal += cb.balance
 			poktFiatTotal += cb.fiatBalance
 		}
 
-		key := fmt.Sprintf("%s-%s", cb.name, currentDate)
-		cryptoVal := persistence.CryptoValues{
+		l.assets[cb.name] = persistence.CryptoValues{
 			CryptoBalance: cb.balance,
 			FiatValue:     cb.fiatValue,
 			FiatBalance:   cb.fiatBalance,
 		}
-
-		err = l.persistence.WriteCryptoValues(key, cryptoVal)
-		if err != nil {
-			fmt.Printf("Error writing crypto values to database: %s\n", err)
-		}
 	}
 
 	hasMultiplePokts := slices.Contains(l.cryptoValues, "WPOKT") && slices.Contains(l.cryptoValues, "POKT")
@@ -260,70 +307,72 @@ func (l *Logger) LogBalances(balances map[string]float64, fiatValues map[string]
 			l.cryptoFiatConversion)
 	}
 
-	// Display exchange balances section if POKT exchange amount is configured
-	exchangeFiatValues := make(map[string]float64)
-	if l.poktExchangeAmount > 0 {
-		fmt.Println("\n<--------- 🌐 Exchange Balances 🌐 --------->")
-
-		exchangeAmountFloat := float64(l.poktExchangeAmount)
-		if fiatValue, ok := exchangeRates[l.cryptoFiatConversion]["POKT"]; ok {
-			exchangeFiatBalance := exchangeAmountFloat * fiatValue
-
-			fmt.Printf("%-*s - %*s @ %s%-*s = %s%-*s %s",
-				cryptoWidth, "POKT",
-				balanceWidth, formatCryptoFloat("POKT", exchangeAmountFloat),
-				fiatSymbols[l.cryptoFiatConversion], fiatValueWidth, formatFiatFloat("POKT", fiatValue),
-				fiatSymbols[l.cryptoFiatConversion], fiatBalanceWidth, formatFiatFloat("", exchangeFiatBalance),
-				l.cryptoFiatConversion)
-
-			// Fetch average values from the previous day for exchange amount
-			previousKey := fmt.Sprintf("POKT-EXCHANGE-%s", previousDate)
-			avgValues, err := l.persistence.GetAverageCryptoValues(previousKey)
-			if err != nil {
-				fmt.Printf(" %sNo data%s\n", colorBlue, colorReset)
-			} else {
-				difference := exchangeFiatBalance - avgValues.FiatBalance
-				color := getColorForDifference(difference)
+	// Display each section of positions held outside of the wallet balances
+	positionFiatValues := make(map[string]float64)
+	var section string
+	for _, position := range positions {
+		fiatValue, ok := exchangeRates[l.cryptoFiatConversion][position.Symbol]
+		if !ok {
+			continue
+		}
 
-				if difference == 0 {
-					fmt.Printf(" %s%s%s\n", color, "0.00", colorReset)
-				} else {
-					fmt.Printf(" %s%s%s\n", color, formatFiatFloat("", difference), colorReset)
-				}
+		if position.Section != section {
+			section = position.Section
+			fmt.Printf("\n<--------- %s --------->\n", section)
+		}
 
-				fiatTotal += avgValues.FiatBalance
-			}
+		positionFiatBalance := position.Amount * fiatValue
 
-			// Store exchange amount data
-			key := fmt.Sprintf("POKT-EXCHANGE-%s", currentDate)
-			cryptoVal := persistence.CryptoValues{
-				CryptoBalance: exchangeAmountFloat,
-				FiatValue:     fiatValue,
-				FiatBalance:   exchangeFiatBalance,
-			}
+		fmt.Printf("%-*s - %*s @ %s%-*s = %s%-*s %s",
+			cryptoWidth, position.Name,
+			balanceWidth, formatCryptoFloat(position.Symbol, position.Amount),
+			fiatSymbols[l.cryptoFiatConversion], fiatValueWidth, formatFiatFloat(position.Symbol, fiatValue),
+			fiatSymbols[l.cryptoFiatConversion], fiatBalanceWidth, formatFiatFloat("", positionFiatBalance),
+			l.cryptoFiatConversion)
+
+		// Fetch average values from the previous day for the position
+		previousKey := fmt.Sprintf("%s-%s", position.Key, previousDate)
+		avgValues, err := l.persistence.GetAverageCryptoValues(previousKey)
+		if err != nil {
+			fmt.Printf(" %sNo data%s\n", colorBlue, colorReset)
+		} else {
+			difference := positionFiatBalance - avgValues.FiatBalance
+			color := getColorForDifference(difference)
 
-			err = l.persistence.WriteCryptoValues(key, cryptoVal)
-			if err != nil {
-				fmt.Printf("Error writing exchange crypto values to database: %s\n", err)
+			if difference == 0 {
+				fmt.Printf(" %s%s%s\n", color, "0.00", colorReset)
+			} else {
+				fmt.Printf(" %s%s%s\n", color, formatFiatFloat("", difference), colorReset)
 			}
 
-			// Calculate exchange fiat values for all currencies
-			for _, fiat := range l.convertCurrencies {
-				if exchangeRate, ok := exchangeRates[fiat]["POKT"]; ok {
-					exchangeFiatValues[fiat] = exchangeAmountFloat * exchangeRate
-				}
+			fiatTotal += avgValues.FiatBalance
+		}
+
+		l.printNotes(position.Name)
+
+		// Keep position data for the snapshot of the run
+		l.assets[position.Key] = persistence.CryptoValues{
+			CryptoBalance: position.Amount,
+			FiatValue:     fiatValue,
+			FiatBalance:   positionFiatBalance,
+		}
+
+		// Calculate position fiat values for all currencies
+		for _, fiat := range l.convertCurrencies {
+			if exchangeRate, ok := exchangeRates[fiat][position.Symbol]; ok {
+				positionFiatValues[fiat] += position.Amount * exchangeRate
 			}
 		}
 	}
 
 	fmt.Println("\n<--------- 💰 Fiat Total Balances 💰 --------->")
-	defaultFiatBalance := fiatValues[l.cryptoFiatConversion] + exchangeFiatValues[l.cryptoFiatConversion]
+	defaultFiatBalance := fiatValues[l.cryptoFiatConversion] + positionFiatValues[l.cryptoFiatConversion]
 	differenceInDefaultFiat := defaultFiatBalance - fiatTotal
 
 	for _, fiat := range l.convertCurrencies {
 		if balance, ok := fiatValues[fiat]; ok {
-			// Add exchange amounts to total balance
-			totalBalance := balance + exchangeFiatValues[fiat]
+			// Add position amounts to total balance
+			totalBalance := balance + positionFiatValues[fiat]
 			fmt.Printf("%s %s - %s%s", fiatEmojis[fiat], fiat, fiatSymbols[fiat], formatFiatFloat("", totalBalance))
 
 			if fiat == l.cryptoFiatConversion {
@@ -334,7 +383,7 @@ func (l *Logger) LogBalances(balances map[string]float64, fiatValues map[string]
 					fmt.Printf(" %s%s%s%s\n", color, fiatSymbols[fiat], formatFiatFloat("", differenceInDefaultFiat), colorReset)
 				}
 			} else {
-				totalBalanceInDefaultFiat := fiatValues[l.cryptoFiatConversion] + exchangeFiatValues[l.cryptoFiatConversion]
+				totalBalanceInDefaultFiat := fiatValues[l.cryptoFiatConversion] + positionFiatValues[l.cryptoFiatConversion]
 				exchangeRate := totalBalance / totalBalanceInDefaultFiat
 				difference := differenceInDefaultFiat * exchangeRate
 				color := getColorForDifference(difference)
@@ -368,7 +417,7 @@ func formatFiatFloat(crypto string, num float64) string {
 	return p.Sprintf(format, num)
 }
 
-func (l *Logger) calculateAlignmentWidths(balances map[string]float64, exchangeRates map[string]map[string]float64) (int, int, int, int) {
+func (l *Logger) calculateAlignmentWidths(balances map[string]float64, positions []Position, exchangeRates map[string]map[string]float64) (int, int, int, int) {
 	maxCryptoWidth := 0
 	maxBalanceWidth := 0
 	maxFiatValueWidth := 0
@@ -408,29 +457,34 @@ func (l *Logger) calculateAlignmentWidths(balances map[string]float64, exchangeR
 		}
 	}
 
-	// Also consider POKT exchange amounts for alignment if configured
-	if l.poktExchangeAmount > 0 {
-		if fiatValue, ok := exchangeRates[l.cryptoFiatConversion]["POKT"]; ok {
-			exchangeAmountFloat := float64(l.poktExchangeAmount)
+	// Also consider positions for alignment
+	for _, position := range positions {
+		fiatValue, ok := exchangeRates[l.cryptoFiatConversion][position.Symbol]
+		if !ok {
+			continue
+		}
 
-			// Check exchange amount balance width
-			exchangeBalanceStr := formatCryptoFloat("POKT", exchangeAmountFloat)
-			if len(exchangeBalanceStr) > maxBalanceWidth {
-				maxBalanceWidth = len(exchangeBalanceStr)
-			}
+		// Check position name width
+		if len(position.Name) > maxCryptoWidth {
+			maxCryptoWidth = len(position.Name)
+		}
 
-			// Check exchange fiat value width
-			exchangeFiatValueStr := formatFiatFloat("POKT", fiatValue)
-			if len(exchangeFiatValueStr) > maxFiatValueWidth {
-				maxFiatValueWidth = len(exchangeFiatValueStr)
-			}
+		// Check position balance width
+		positionBalanceStr := formatCryptoFloat(position.Symbol, position.Amount)
+		if len(positionBalanceStr) > maxBalanceWidth {
+			maxBalanceWidth = len(positionBalanceStr)
+		}
 
-			// Check exchange fiat balance width
-			exchangeFiatBalance := exchangeAmountFloat * fiatValue
-			exchangeFiatBalanceStr := formatFiatFloat("", exchangeFiatBalance)
-			if len(exchangeFiatBalanceStr) > maxFiatBalanceWidth {
-				maxFiatBalanceWidth = len(exchangeFiatBalanceStr)
-			}
+		// Check position fiat value width
+		positionFiatValueStr := formatFiatFloat(position.Symbol, fiatValue)
+		if len(positionFiatValueStr) > maxFiatValueWidth {
+			maxFiatValueWidth = len(positionFiatValueStr)
+		}
+
+		// Check position fiat balance width
+		positionFiatBalanceStr := formatFiatFloat("", position.Amount*fiatValue)
+		if len(positionFiatBalanceStr) > maxFiatBalanceWidth {
+			maxFiatBalanceWidth = len(positionFiatBalanceStr)
 		}
 	}
 
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/commoddity/bank-informer/pokt"
+)
+
+const morseSection = "🏚️ Unclaimed Morse Balances 🏚️"
+
+// MorsePositions returns a position for each non-zero unclaimed balance or stake of the Morse accounts.
+func MorsePositions(accounts []pokt.MorseAccount) []Position {
+	var positions []Position
+	for _, account := range accounts {
+		if account.Claimed {
+			continue
+		}
+
+		for _, amount := range []struct {
+			name   string
+			key    string
+			amount float64
+		}{
+			{"Unstaked", "UNSTAKED", account.UnstakedBalance},
+			{"Supplier Stake", "SUPPLIER-STAKE", account.SupplierStake},
+			{"App Stake", "APP-STAKE", account.ApplicationStake},
+		} {
+			if amount.amount == 0 {
+				continue
+			}
+			positions = append(positions, Position{
+				Section: morseSection,
+				Name:    fmt.Sprintf("Morse %s %s", shortAddress(account.Address), amount.name),
+				Key:     fmt.Sprintf("MORSE-%s-%s", account.Address, amount.key),
+				Symbol:  "POKT",
+				Amount:  amount.amount,
+			})
+		}
+	}
+	return positions
+}
+
+// LogMorseClaims logs the Shannon destination of each claimed Morse account.
+func (l *Logger) LogMorseClaims(accounts []pokt.MorseAccount) {
+	var claimed []pokt.MorseAccount
+	for _, account := range accounts {
+		if account.Claimed {
+			claimed = append(claimed, account)
+		}
+	}
+	if len(claimed) == 0 {
+		return
+	}
+
+	fmt.Println("\n<--------- 🏡 Claimed Morse Accounts 🏡 --------->")
+	for _, account := range claimed {
+		fmt.Printf("Morse %s - %s POKT claimed at height %d ➡️  %s\n",
+			shortAddress(account.Address),
+			formatCryptoFloat("POKT", account.UnstakedBalance+account.SupplierStake+account.ApplicationStake),
+			account.ClaimedAtHeight,
+			account.ShannonDestAddress)
+	}
+}
+
+func shortAddress(address string) string {
+	if len(address) <= 8 {
+		return address
+	}
+	return address[:8]
+}
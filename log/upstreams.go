@@ -0,0 +1,66 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/commoddity/bank-informer/client"
+	"github.com/commoddity/bank-informer/persistence"
+)
+
+// upstreamHistoryRuns is the number of previous runs the calls of a run are compared to.
+const upstreamHistoryRuns = 10
+
+// LogUpstreamSummary logs a table of the calls made to each upstream during the run,
+// with the average latency and error rate of the upstream over its previous runs.
+func LogUpstreamSummary(summaries []client.UpstreamSummary, history []persistence.UpstreamStats) {
+	if len(summaries) == 0 {
+		return
+	}
+
+	// Keep the last runs of each upstream, as history is ordered oldest first
+	previous := make(map[string][]persistence.UpstreamStats)
+	for _, stats := range history {
+		runs := append(previous[stats.Upstream], stats)
+		if len(runs) > upstreamHistoryRuns {
+			runs = runs[1:]
+		}
+		previous[stats.Upstream] = runs
+	}
+
+	fmt.Println("\n<--------- 📡 Upstream Calls 📡 --------->")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Upstream\tCalls\tErrors\tAttempts\tAvg Latency\tMax Latency\tBytes\tLast %d Runs Avg Latency\tLast %d Runs Error Rate\n",
+		upstreamHistoryRuns, upstreamHistoryRuns)
+	for _, summary := range summaries {
+		color := colorReset
+		if summary.Errors > 0 {
+			color = colorRed
+		}
+		avgLatency := summary.TotalLatency / time.Duration(summary.Calls)
+		prevLatency, prevErrorRate := previousAverages(previous[summary.Upstream])
+		fmt.Fprintf(w, "%s\t%d\t%s%d%s\t%d\t%s\t%s\t%d\t%s\t%s\n",
+			summary.Upstream, summary.Calls, color, summary.Errors, colorReset, summary.Attempts,
+			avgLatency.Round(time.Millisecond), summary.MaxLatency.Round(time.Millisecond), summary.Bytes,
+			prevLatency, prevErrorRate)
+	}
+	w.Flush()
+}
+
+// previousAverages returns the average latency and error rate of the calls of the previous runs.
+func previousAverages(runs []persistence.UpstreamStats) (string, string) {
+	var calls, errors int
+	var latency time.Duration
+	for _, run := range runs {
+		calls += run.Calls
+		errors += run.Errors
+		latency += run.TotalLatency
+	}
+	if calls == 0 {
+		return "-", "-"
+	}
+	return (latency / time.Duration(calls)).Round(time.Millisecond).String(),
+		fmt.Sprintf("%.1f%%", float64(errors)/float64(calls)*100)
+}
@@ -1,24 +1,58 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/commoddity/bank-informer/backfill"
+	"github.com/commoddity/bank-informer/btc"
 	"github.com/commoddity/bank-informer/client"
 	"github.com/commoddity/bank-informer/cmc"
 	"github.com/commoddity/bank-informer/config"
 	"github.com/commoddity/bank-informer/csv"
 	"github.com/commoddity/bank-informer/eth"
 	"github.com/commoddity/bank-informer/log"
+	"github.com/commoddity/bank-informer/onchain"
 	"github.com/commoddity/bank-informer/persistence"
 	"github.com/commoddity/bank-informer/pokt"
+	"github.com/commoddity/bank-informer/price"
 	"github.com/commoddity/bank-informer/setup"
+	"github.com/commoddity/bank-informer/solana"
 )
 
 // This program retrieves and logs the balances of ETH and POKT wallets.
 // It also fetches the exchange rates for a list of currencies and calculates
 // the fiat values for each balance. The balances, fiat values, and exchange rates
 // are then logged for further use.
+//
+// Running "bank-informer backfill -from YYYY-MM-DD [-to YYYY-MM-DD]" instead
+// populates past days with the POKT wallet balance at the end of each day, and
+// "bank-informer backfill-prices" with the same flags fills in missing prices.
 func main() {
+	offline := flag.Bool("offline", false, "price balances at the last cached exchange rates")
+	debug := flag.Bool("debug", false, "print the HTTP client's rate limit waits and circuit breaker changes to stderr, but not each call (see -verbose)")
+	record := flag.String("record", "", "record all HTTP requests and responses to a cassette file")
+	replay := flag.String("replay", "", "serve HTTP responses from a recorded cassette file instead of the network")
+	verbose := flag.Bool("verbose", false, "print each upstream call to stderr as it finishes, but not rate limit waits or circuit breaker changes (see -debug)")
+	flag.Parse()
+
+	if *record != "" && *replay != "" {
+		panic(fmt.Errorf("-record and -replay cannot be used together"))
+	}
+	// Backfills write to the CSV file from the database, which is not kept for recorded and replayed runs
+	if (*record != "" || *replay != "") && backfill.IsCommand(flag.Arg(0)) {
+		panic(fmt.Errorf("-record and -replay cannot be used with %s", flag.Arg(0)))
+	}
+
 	// Setup .env file if it doesn't exist
 	setup.Start()
 
@@ -28,12 +62,114 @@ func main() {
 		panic(err)
 	}
 
-	// Initialize persistence module
-	persistence := persistence.NewPersistence()
-	defer persistence.Close()
+	// Initialize persistence module. Recorded and replayed runs use an empty in-memory
+	// database, so that a replay sends the same requests as its recording and neither
+	// stores data from a demo run.
+	persistence := openPersistence(*record != "" || *replay != "")
+
+	// Convert the daily values stored by earlier versions to snapshots
+	migrated, err := persistence.MigrateDailyValues()
+	if err != nil {
+		panic(err)
+	}
+	if migrated > 0 {
+		fmt.Printf("🗃️  Migrated %d daily values to snapshots\n", migrated)
+	}
+
+	// Trace every upstream call for the summary at the end of the run
+	var verboseOutput io.Writer
+	if *verbose {
+		verboseOutput = os.Stderr
+	}
+	tracer := client.NewTracer(verboseOutput)
+	runTime := time.Now()
+
+	// Create the HTTP client shared by all upstreams
+	clientConfig := config.ClientConfig()
+	clientConfig.BreakerStore = persistence
+	clientConfig.Record = *record
+	clientConfig.Replay = *replay
+	clientConfig.Tracer = tracer
+	if *debug {
+		clientConfig.Debug = os.Stderr
+	}
+	httpClient, err := client.New(clientConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	// Cancel the run on Ctrl-C or SIGTERM so that persistence is still closed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// Run a backfill command instead of fetching the current balances.
+	// Backfills may take a long time, so they are not limited by the run timeout.
+	switch flag.Arg(0) {
+	case backfill.CommandBalances, backfill.CommandPrices:
+		err = backfill.Run(ctx, flag.Arg(0), flag.Args()[1:], config, persistence, httpClient)
+	default:
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.RunTimeout)
+		err = run(ctx, config, persistence, httpClient, *offline, *replay != "")
+		cancel()
+	}
+	stop()
+
+	// Log the upstream calls of the run next to those of the previous runs, and store them, even if the run failed
+	summaries := tracer.Summary()
+	history, historyErr := persistence.GetUpstreamStats(time.Time{})
+	if historyErr != nil {
+		fmt.Printf("❌ Failed to get upstream stats of previous runs: %s\n", historyErr)
+	}
+	log.LogUpstreamSummary(summaries, history)
+	if writeErr := persistence.WriteUpstreamSummaries(runTime, summaries); writeErr != nil {
+		fmt.Printf("❌ Failed to store upstream stats: %s\n", writeErr)
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Printf("\n❌ Run did not finish within the run timeout of %s: %s\n", config.RunTimeout, err)
+	case errors.Is(err, context.Canceled):
+		fmt.Printf("\n🛑 Run cancelled\n")
+	case err != nil:
+		fmt.Printf("\n❌ %s\n", err)
+	}
+
+	// Explain upstream errors that are fixed in the config file
+	var httpErr *client.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.IsAuth():
+			fmt.Printf("🔑 %s rejected the request, check its API key and plan in the config file\n", httpErr.Host)
+		case httpErr.IsRateLimited():
+			fmt.Printf("⏳ %s rate limited the request, consider a rate_limit for it in upstreams\n", httpErr.Host)
+		}
+	}
+
+	if closeErr := persistence.Close(); closeErr != nil {
+		fmt.Printf("❌ Failed to close the database: %s\n", closeErr)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
 
+// run fetches the current balances and exchange rates, then logs and stores them.
+// Replayed runs are not written to the CSV file.
+func run(ctx context.Context, config *config.Config, persistence *persistence.Persistence, httpClient *http.Client, offline, replay bool) error {
 	// Add 1 to chanLength to account for the call to get exchange rates
 	chanLength := len(config.CryptoValues) + len(config.ConvertCurrencies)
+	if config.PoktTrackStaking {
+		chanLength++
+	}
+	if len(config.PoktIncomeAddresses) > 0 {
+		chanLength++
+	}
+	if len(config.MorseAddresses) > 0 {
+		chanLength++
+	}
+	if len(config.SolanaWalletAddresses) > 0 {
+		chanLength++
+	}
 	progressChan := make(chan string, chanLength)
 
 	// Initialize logger
@@ -41,7 +177,8 @@ func main() {
 		CryptoFiatConversion: config.CryptoFiatConversion,
 		ConvertCurrencies:    config.ConvertCurrencies,
 		CryptoValues:         config.CryptoValues,
-		PoktExchangeAmount:   config.PoktExchangeAmount,
+		Stablecoins:          config.Stablecoins,
+		DepegThresholdBps:    config.DepegThresholdBps,
 	}, persistence, progressChan, chanLength)
 
 	// Start the progress bar in a goroutine
@@ -58,7 +195,6 @@ func main() {
 	var wg sync.WaitGroup
 
 	// Create ETH client
-	httpClient := client.New()
 	ethConfig := eth.Config{
 		PathApiUrl:       config.PathApiUrl,
 		PathApiKey:       config.PathApiKey,
@@ -77,30 +213,155 @@ func main() {
 	}
 	poktClient := pokt.NewClient(poktConfig, progressChan, &mu, &wg)
 
-	// Create CMC client
-	cmcConfig := cmc.Config{
-		CMCAPIKey:         config.CMCAPIKey,
+	// Create BTC client
+	btcConfig := btc.Config{
+		EsploraApiUrl: config.BTCEsploraApiUrl,
+		Addresses:     config.BTCAddresses,
+		XPubs:         config.BTCXPubs,
+		GapLimit:      config.BTCGapLimit,
+		HttpClient:    httpClient,
+	}
+	btcClient := btc.NewClient(btcConfig, progressChan, &mu, &wg)
+
+	// Create Solana client
+	solanaConfig := solana.Config{
+		RpcUrl:          config.SolanaRpcUrl,
+		PathApiUrl:      config.PathApiUrl,
+		PathApiKey:      config.PathApiKey,
+		ServiceID:       config.SolanaServiceID,
+		WalletAddresses: config.SolanaWalletAddresses,
+		TokenMints:      config.SolanaTokenMints,
+		HttpClient:      httpClient,
+	}
+	solanaClient := solana.NewClient(solanaConfig, progressChan, &mu, &wg)
+
+	// Create price client with the configured price provider
+	var providers []price.Provider
+	for _, name := range config.PriceProviders {
+		providers = append(providers, price.NewProvider(name, config, persistence, httpClient))
+	}
+	var priceProvider price.Provider
+	var consensus *price.Consensus
+	if len(providers) > 1 {
+		// Use the median quote of multiple providers
+		consensus = price.NewConsensus(providers, config.PriceDivergenceThreshold, persistence)
+		priceProvider = consensus
+	} else {
+		priceProvider = providers[0]
+	}
+	priceConfig := price.Config{
+		Provider:          priceProvider,
 		ConvertCurrencies: config.ConvertCurrencies,
-		HttpClient:        httpClient,
+		Cache:             persistence,
+		CacheMaxAge:       config.RateCacheMaxAge,
+		Offline:           offline,
+		FX:                price.NewFXProvider(config, persistence, httpClient),
+		FXStore:           persistence,
+		BaseCurrency:      config.CryptoFiatConversion,
+		Aliases:           config.PriceAlias,
 	}
-	cmcClient := cmc.NewClient(cmcConfig, progressChan, &mu, &wg)
+	priceClient := price.NewClient(priceConfig, progressChan, &mu, &wg)
 
-	// Retrieve and store ERC20 wallet balances through Grove Portal
-	err = ethClient.GetETHWalletBalances(balances)
+	// Retrieve the wallet balances of each source: ERC20 and POKT balances through
+	// Grove Portal, BTC address and extended public key balances through Esplora,
+	// and SOL and SPL token balances
+	sources := []balanceSource{
+		{name: "eth", fetch: ethClient.GetETHWalletBalances},
+		{name: "pokt", fetch: poktClient.GetWalletBalance},
+	}
+	if _, ok := balances["BTC"]; ok && (len(config.BTCAddresses) > 0 || len(config.BTCXPubs) > 0) {
+		sources = append(sources, balanceSource{name: "btc", fetch: btcClient.GetWalletBalance})
+	}
+	if len(config.SolanaWalletAddresses) > 0 {
+		sources = append(sources, balanceSource{name: "solana", fetch: solanaClient.GetWalletBalances})
+	}
+	snapshot := persistence.NewRunSnapshot()
+	fallbacks, err := getBalances(ctx, sources, balances, persistence, snapshot)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	snapshot.Height("eth", ethClient.BlockHeight())
+	snapshot.Height("pokt", poktClient.BlockHeight())
+	snapshot.Height("solana", solanaClient.Slot())
 
-	// Retrieve and store POKT wallet balance through Grove Portal
-	err = poktClient.GetWalletBalance(balances)
-	if err != nil {
-		panic(err)
+	// Create a slice to store positions held outside of the wallet balances
+	var positions []log.Position
+	if exchangeAmount := poktClient.GetExchangeAmount(); exchangeAmount > 0 {
+		positions = append(positions, log.Position{
+			Section: "🌐 Exchange Balances 🌐",
+			Name:    "POKT",
+			Key:     "POKT-EXCHANGE",
+			Symbol:  "POKT",
+			Amount:  float64(exchangeAmount),
+		})
+	}
+
+	// Retrieve the delegated, unbonding and claimable reward POKT amounts
+	if config.PoktTrackStaking {
+		staking, err := poktClient.GetStakingBalances(ctx)
+		switch {
+		case errors.Is(err, client.ErrCircuitOpen):
+			fmt.Printf("⚠️  %s, skipping staking balances\n", err)
+			snapshot.Skipped("pokt-staking", err)
+		case err != nil:
+			return err
+		default:
+			snapshot.Fetched("pokt-staking")
+			positions = append(positions,
+				log.Position{Section: "🥩 Staking Balances 🥩", Name: "POKT Delegated", Key: "POKT-DELEGATED", Symbol: "POKT", Amount: staking.Delegated},
+				log.Position{Section: "🥩 Staking Balances 🥩", Name: "POKT Unbonding", Key: "POKT-UNBONDING", Symbol: "POKT", Amount: staking.Unbonding},
+				log.Position{Section: "🥩 Staking Balances 🥩", Name: "POKT Rewards", Key: "POKT-REWARDS", Symbol: "POKT", Amount: staking.Rewards},
+			)
+		}
+	}
+
+	// Retrieve the unclaimed Morse balances and stakes
+	var morseAccounts []pokt.MorseAccount
+	if len(config.MorseAddresses) > 0 {
+		morseAccounts, err = poktClient.GetMorseAccounts(ctx, config.MorseAddresses)
+		switch {
+		case errors.Is(err, client.ErrCircuitOpen):
+			fmt.Printf("⚠️  %s, skipping Morse accounts\n", err)
+			snapshot.Skipped("pokt-morse", err)
+		case err != nil:
+			return err
+		default:
+			snapshot.Fetched("pokt-morse")
+			positions = append(positions, log.MorsePositions(morseAccounts)...)
+		}
+	}
+
+	// Retrieve the POKT reward events since the last searched height
+	var rewardEvents []pokt.RewardEvent
+	var rewardHeight int64
+	if len(config.PoktIncomeAddresses) > 0 {
+		lastHeight, err := persistence.GetLastIncomeHeight()
+		if err != nil {
+			return err
+		}
+		if lastHeight == 0 {
+			// Only search from the configured height on the first run
+			lastHeight = config.PoktIncomeStartHeight
+		}
+		rewardEvents, rewardHeight, err = poktClient.GetRewardEvents(ctx, config.PoktIncomeAddresses, lastHeight)
+		switch {
+		case errors.Is(err, client.ErrCircuitOpen):
+			// Search from the same height on the next run
+			fmt.Printf("⚠️  %s, skipping new reward events\n", err)
+			snapshot.Skipped("pokt-income", err)
+			rewardHeight = lastHeight
+		case err != nil:
+			return err
+		default:
+			snapshot.Fetched("pokt-income")
+			snapshot.Height("pokt-income", rewardHeight)
+		}
 	}
 
 	// Retrieve and store the exchange rates for the current currency
-	exchangeRates, err := cmcClient.GetAllExchangeRates(balances)
+	exchangeRates, err := priceClient.GetAllExchangeRates(ctx, balances)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// Wait for all goroutines to finish
@@ -110,20 +371,176 @@ func main() {
 	close(progressChan)
 
 	// Calculate the fiat values for each balance
-	fiatValues := cmcClient.GetFiatValues(balances, exchangeRates)
+	fiatValues := priceClient.GetFiatValues(balances, exchangeRates)
+
+	// Label the age of the exchange rates if they were cached
+	if cachedAt, ok := priceClient.CachedAt(); ok {
+		logger.SetRatesCachedAt(cachedAt)
+		snapshot.Cached(priceProvider.Name(), cachedAt)
+	} else {
+		snapshot.Fetched(priceProvider.Name())
+	}
+
+	// Warn if cached exchange rates were used to stay within the credit budget
+	if err := priceClient.BudgetExceeded(); err != nil {
+		fmt.Printf("⚠️  %s, using cached exchange rates\n", err)
+	}
+
+	// Warn if cached exchange rates were used because a price upstream is down
+	if err := priceClient.CircuitOpen(); err != nil {
+		fmt.Printf("⚠️  %s, using cached exchange rates\n", err)
+	}
+
+	// Flag the balances of sources that were down with their last known balances
+	for _, fallback := range fallbacks {
+		for symbol, balance := range fallback.Balances {
+			if _, ok := balances[symbol]; ok && balance != 0 {
+				logger.Flag(symbol, fmt.Sprintf("%s includes the last known %s balance from %s (circuit breaker open)",
+					symbol, fallback.Source, fallback.Time.Format("2006-01-02 15:04")))
+			}
+		}
+	}
+
+	// Note the assets priced as another symbol
+	for alias, canonical := range config.PriceAlias {
+		if _, ok := balances[alias]; ok {
+			logger.Flag(alias, fmt.Sprintf("%s is priced as %s (price_alias)", alias, canonical))
+		}
+	}
+
+	// Flag the assets whose on-chain quotes were too old to use
+	for _, provider := range providers {
+		if onchainClient, ok := provider.(*onchain.Client); ok {
+			for _, stale := range onchainClient.Stale() {
+				logger.Flag(stale.Symbol, stale.String())
+			}
+		}
+	}
+
+	// Flag the assets whose provider quotes diverged
+	if consensus != nil {
+		for _, divergence := range consensus.Divergences() {
+			logger.Flag(divergence.Symbol, divergence.String())
+		}
+		for _, err := range consensus.OverBudget() {
+			fmt.Printf("⚠️  %s, using the remaining price providers\n", err)
+		}
+		for _, err := range consensus.Failures() {
+			fmt.Printf("⚠️  Price provider failed, using the remaining providers: %s\n", err)
+		}
+	}
 
 	// Log the balances, fiat values, and exchange rates
-	logger.LogBalances(balances, fiatValues, exchangeRates)
+	logger.LogBalances(balances, positions, fiatValues, exchangeRates)
 
-	// Write the balances, fiat values, and exchange rates to a CSV file
-	err = csv.WriteCryptoValuesToCSV(persistence, config.CryptoValues)
+	// Store the snapshot of the run before income is valued from it
+	err = snapshot.Write(config.CryptoFiatConversion, logger.Assets(), exchangeRates)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	// Log where the funds of claimed Morse accounts went
+	logger.LogMorseClaims(morseAccounts)
+
+	// Store and log the POKT reward income
+	if len(config.PoktIncomeAddresses) > 0 {
+		// Value income at the historical prices of the first provider that has them
+		var historical price.HistoricalProvider
+		for _, provider := range providers {
+			if historicalProvider, ok := provider.(price.HistoricalProvider); ok && !offline {
+				historical = historicalProvider
+				break
+			}
+		}
+		logger.LogIncome(ctx, rewardEvents, exchangeRates, historical)
+
+		err = persistence.WriteLastIncomeHeight(rewardHeight)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Log the CoinMarketCap credits used today and this month
+	if config.UsesCMC() {
+		today, month, err := cmc.CreditUsage(persistence)
+		if err != nil {
+			return err
+		}
+		logger.LogCreditUsage("CoinMarketCap", today, month, config.CMCMonthlyCreditBudget)
+	}
+
+	// Write the balances, fiat values, and exchange rates to a CSV file
+	if !replay {
+		err = csv.WriteCryptoValuesToCSV(persistence, config.CryptoValues)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Clear BadgerDB of old entries (older than 72 hours)
-	err = persistence.ClearOldEntries()
-	if err != nil {
-		panic(err)
+	return persistence.ClearOldEntries()
+}
+
+// balanceSource is an upstream that wallet balances are fetched from.
+type balanceSource struct {
+	name  string
+	fetch func(ctx context.Context, balances map[string]float64) error
+}
+
+// getBalances fetches the balances of each source and adds them to balances. The
+// balances of each source are stored, and if the circuit breaker of a source's
+// upstream is open, its last stored balances are used instead and returned.
+func getBalances(ctx context.Context, sources []balanceSource, balances map[string]float64, p *persistence.Persistence, snapshot *persistence.RunSnapshot) ([]persistence.LastBalances, error) {
+	var fallbacks []persistence.LastBalances
+
+	for _, source := range sources {
+		sourceBalances := make(map[string]float64)
+		for symbol := range balances {
+			sourceBalances[symbol] = 0
+		}
+
+		err := source.fetch(ctx, sourceBalances)
+		switch {
+		case errors.Is(err, client.ErrCircuitOpen):
+			last, lastErr := p.GetLastBalances(source.name)
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w, and no last known %s balances are stored", err, source.name)
+			}
+			fallbacks = append(fallbacks, last)
+			snapshot.LastKnown(source.name, last.Time)
+			sourceBalances = last.Balances
+		case err != nil:
+			return nil, err
+		default:
+			err = p.WriteLastBalances(persistence.LastBalances{
+				Source:   source.name,
+				Time:     time.Now(),
+				Balances: sourceBalances,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to store %s balances: %w", source.name, err)
+			}
+			snapshot.Fetched(source.name)
+		}
+
+		for symbol, balance := range sourceBalances {
+			if _, ok := balances[symbol]; ok {
+				balances[symbol] += balance
+			}
+		}
+	}
+
+	return fallbacks, nil
+}
+
+// openPersistence opens the database, or an empty in-memory database if inMemory is set.
+func openPersistence(inMemory bool) *persistence.Persistence {
+	if inMemory {
+		p, err := persistence.NewInMemoryPersistence()
+		if err != nil {
+			panic(err)
+		}
+		return p
 	}
+	return persistence.NewPersistence()
 }
@@ -0,0 +1,284 @@
+package onchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/commoddity/bank-informer/eth"
+)
+
+const (
+	providerName = "onchain"
+
+	// Function selectors of the contract calls
+	latestRoundDataSelector = "0xfeaf968c"
+	decimalsSelector        = "0x313ce567"
+
+	usd = "USD"
+)
+
+// defaultFeeds maps symbols to the Ethereum mainnet Chainlink aggregator of their USD price.
+// Fiat currency feeds are used to convert USD prices to other fiat currencies.
+var defaultFeeds = map[string]string{
+	"ETH":  "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419",
+	"BTC":  "0xF4030086522a5bEEa4988F8cA5B36dbC97BeE88c",
+	"USDC": "0x8fFfFfd4AfB6115b954Bd326cbe7B4BA576818f6",
+	"USDT": "0x3E7d1eAB13ad0104d2750B8863b489D65364e32D",
+	"EUR":  "0xb49f677943BC038e9857d61E7d053CaA2C1734C1",
+	"GBP":  "0x5c0Ab2d9b5a7ed9f470386e82BB36A3613cDd4b5",
+	"JPY":  "0xBcE206caE7f0ec07b545EddE332A47C2F75bbeb3",
+	"CHF":  "0x449d117117838fFA61263B61dA6301AA2a88B13A",
+}
+
+// Caller executes read-only contract calls on Ethereum.
+type Caller interface {
+	BatchEthCall(ctx context.Context, calls []eth.EthCall) ([]string, error)
+}
+
+type Config struct {
+	Caller Caller
+	// Feeds maps symbols to a Chainlink USD aggregator, and overrides the default feeds.
+	Feeds map[string]string
+	// Pools maps symbols without a feed to the Uniswap pool they are priced from.
+	Pools map[string]Pool
+	// MaxQuoteAge is the age after which a quote is stale and not used.
+	MaxQuoteAge time.Duration
+}
+
+// Quote is an on-chain price along with the time the oldest input to it was last updated.
+type Quote struct {
+	Price     float64
+	UpdatedAt time.Time
+}
+
+// StaleQuote is a quote that was not used because it was older than the max quote age.
+type StaleQuote struct {
+	Symbol    string
+	UpdatedAt time.Time
+	MaxAge    time.Duration
+}
+
+// Client is a price provider reading Chainlink feeds and Uniswap pools over
+// Ethereum JSON-RPC, which does not need a price API key.
+type Client struct {
+	Config Config
+	feeds  map[string]string
+	mutex  sync.Mutex
+	stale  []StaleQuote
+}
+
+func NewClient(config Config) *Client {
+	feeds := make(map[string]string)
+	for symbol, feed := range defaultFeeds {
+		feeds[symbol] = feed
+	}
+	for symbol, feed := range config.Feeds {
+		feeds[symbol] = feed
+	}
+
+	return &Client{
+		Config: config,
+		feeds:  feeds,
+	}
+}
+
+func (c *Client) Name() string {
+	return providerName
+}
+
+// GetQuotes returns the on-chain price of each symbol in fiat, leaving out
+// stale quotes, which are reported by Stale.
+func (c *Client) GetQuotes(ctx context.Context, symbols []string, fiat string) (map[string]float64, error) {
+	quotes, err := c.GetTimedQuotes(ctx, symbols, fiat)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]float64)
+	for symbol, quote := range quotes {
+		if c.Config.MaxQuoteAge > 0 && time.Since(quote.UpdatedAt) > c.Config.MaxQuoteAge {
+			c.mutex.Lock()
+			c.stale = append(c.stale, StaleQuote{Symbol: symbol, UpdatedAt: quote.UpdatedAt, MaxAge: c.Config.MaxQuoteAge})
+			c.mutex.Unlock()
+			continue
+		}
+		prices[symbol] = quote.Price
+	}
+
+	return prices, nil
+}
+
+// GetTimedQuotes returns the on-chain price of each symbol in fiat with a feed or pool.
+// Prices in fiat currencies other than USD are converted with the fiat currency's feed.
+func (c *Client) GetTimedQuotes(ctx context.Context, symbols []string, fiat string) (map[string]Quote, error) {
+	var fiatQuote Quote
+	if fiat != usd {
+		if _, ok := c.feeds[fiat]; !ok {
+			return nil, fmt.Errorf("no Chainlink feed known for %s/USD", fiat)
+		}
+	}
+
+	// Read every feed needed for the symbols, the pool quote symbols and the fiat currency
+	feedSymbols := []string{}
+	for _, symbol := range symbols {
+		if _, ok := c.feeds[symbol]; ok {
+			feedSymbols = appendUnique(feedSymbols, symbol)
+		} else if pool, ok := c.Config.Pools[symbol]; ok && pool.Quote != usd {
+			feedSymbols = appendUnique(feedSymbols, pool.Quote)
+		}
+	}
+	if fiat != usd {
+		feedSymbols = appendUnique(feedSymbols, fiat)
+	}
+
+	usdQuotes, err := c.getFeedQuotes(ctx, feedSymbols)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, symbol := range symbols {
+		pool, ok := c.Config.Pools[symbol]
+		if _, hasFeed := c.feeds[symbol]; hasFeed || !ok {
+			continue
+		}
+		quote, err := c.getPoolQuote(ctx, symbol, pool, usdQuotes)
+		if err != nil {
+			return nil, err
+		}
+		usdQuotes[symbol] = quote
+	}
+
+	if fiat != usd {
+		fiatQuote = usdQuotes[fiat]
+		if fiatQuote.Price == 0 {
+			return nil, fmt.Errorf("Chainlink %s/USD feed returned a zero price", fiat)
+		}
+	}
+
+	quotes := make(map[string]Quote)
+	for _, symbol := range symbols {
+		quote, ok := usdQuotes[symbol]
+		if !ok {
+			continue
+		}
+		if fiat != usd {
+			quote = Quote{
+				Price:     quote.Price / fiatQuote.Price,
+				UpdatedAt: oldest(quote.UpdatedAt, fiatQuote.UpdatedAt),
+			}
+		}
+		quotes[symbol] = quote
+	}
+
+	return quotes, nil
+}
+
+// Stale returns the quotes that were left out for being older than the max quote age.
+func (c *Client) Stale() []StaleQuote {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stale
+}
+
+func (s StaleQuote) String() string {
+	return fmt.Sprintf("%s on-chain price was last updated at %s, over %s ago, and was not used",
+		s.Symbol, s.UpdatedAt.Format("2006-01-02 15:04:05"), s.MaxAge)
+}
+
+// getFeedQuotes reads the latest round and decimals of the feed of each symbol.
+func (c *Client) getFeedQuotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	var calls []eth.EthCall
+	for _, symbol := range symbols {
+		feed, ok := c.feeds[symbol]
+		if !ok {
+			return nil, fmt.Errorf("no Chainlink feed known for %s/USD", symbol)
+		}
+		calls = append(calls,
+			eth.EthCall{To: feed, Data: latestRoundDataSelector},
+			eth.EthCall{To: feed, Data: decimalsSelector},
+		)
+	}
+
+	results, err := c.Config.Caller.BatchEthCall(ctx, calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Chainlink feeds: %w", err)
+	}
+
+	quotes := make(map[string]Quote)
+	for i, symbol := range symbols {
+		// latestRoundData returns (roundId, answer, startedAt, updatedAt, answeredInRound)
+		round, err := decodeWords(results[2*i], 5)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s/USD round: %w", symbol, err)
+		}
+		decimals, err := decodeWords(results[2*i+1], 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s/USD decimals: %w", symbol, err)
+		}
+
+		answer := toSigned(round[1])
+		if answer.Sign() <= 0 {
+			return nil, fmt.Errorf("Chainlink %s/USD feed returned an invalid answer: %s", symbol, answer)
+		}
+
+		quotes[symbol] = Quote{
+			Price:     scale(answer, decimals[0].Int64()),
+			UpdatedAt: time.Unix(round[3].Int64(), 0),
+		}
+	}
+
+	return quotes, nil
+}
+
+// decodeWords decodes the first count 32-byte words of an ABI-encoded result.
+func decodeWords(result string, count int) ([]*big.Int, error) {
+	data := strings.TrimPrefix(result, "0x")
+	if len(data) < count*64 {
+		return nil, fmt.Errorf("result too short: %s", result)
+	}
+
+	words := make([]*big.Int, count)
+	for i := range words {
+		word, ok := new(big.Int).SetString(data[i*64:(i+1)*64], 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid word: %s", data[i*64:(i+1)*64])
+		}
+		words[i] = word
+	}
+	return words, nil
+}
+
+// toSigned interprets a word as a two's complement int256.
+func toSigned(word *big.Int) *big.Int {
+	if word.Bit(255) == 0 {
+		return word
+	}
+	return new(big.Int).Sub(word, new(big.Int).Lsh(big.NewInt(1), 256))
+}
+
+// scale returns value divided by 10^decimals.
+func scale(value *big.Int, decimals int64) float64 {
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(decimals), nil)
+	result, _ := new(big.Float).Quo(new(big.Float).SetInt(value), new(big.Float).SetInt(divisor)).Float64()
+	return result
+}
+
+func oldest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,167 @@
+package onchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/commoddity/bank-informer/eth"
+)
+
+const (
+	token0Selector       = "0x0dfe1681"
+	token1Selector       = "0xd21220a7"
+	getReservesSelector  = "0x0902f1ac"
+	slot0Selector        = "0x3850c7bd"
+	observationsSelector = "0x252c09d7"
+)
+
+// Versions of the Uniswap pools that tokens are priced from
+const (
+	PoolVersionV2 = "v2"
+	PoolVersionV3 = "v3"
+)
+
+// Pool is a Uniswap pool that prices Token in Quote, which is either USD or a symbol with a feed.
+type Pool struct {
+	Address string
+	Version string
+	Token   string
+	Quote   string
+}
+
+// getPoolQuote prices the pool's token in USD from the pool reserves or current price,
+// and the USD price of the pool's quote token. V2 quotes are as old as the last reserve
+// update, and V3 quotes as the pool's latest oracle observation, which is written by the
+// first swap that moves the price in a block. Quotes in a token with a feed are no newer
+// than the feed.
+func (c *Client) getPoolQuote(ctx context.Context, symbol string, pool Pool, usdQuotes map[string]Quote) (Quote, error) {
+	stateSelector := getReservesSelector
+	if pool.Version == PoolVersionV3 {
+		stateSelector = slot0Selector
+	}
+
+	results, err := c.Config.Caller.BatchEthCall(ctx, []eth.EthCall{
+		{To: pool.Address, Data: token0Selector},
+		{To: pool.Address, Data: token1Selector},
+		{To: pool.Address, Data: stateSelector},
+	})
+	if err != nil {
+		return Quote{}, fmt.Errorf("failed to read %s pool %s: %w", symbol, pool.Address, err)
+	}
+
+	tokens := make([]string, 2)
+	for i := range tokens {
+		words, err := decodeWords(results[i], 1)
+		if err != nil {
+			return Quote{}, fmt.Errorf("failed to decode %s pool token: %w", symbol, err)
+		}
+		tokens[i] = fmt.Sprintf("0x%040x", words[0])
+	}
+
+	var isToken0 bool
+	switch {
+	case strings.EqualFold(tokens[0], pool.Token):
+		isToken0 = true
+	case strings.EqualFold(tokens[1], pool.Token):
+		isToken0 = false
+	default:
+		return Quote{}, fmt.Errorf("%s pool %s does not hold token %s", symbol, pool.Address, pool.Token)
+	}
+
+	calls := []eth.EthCall{
+		{To: tokens[0], Data: decimalsSelector},
+		{To: tokens[1], Data: decimalsSelector},
+	}
+	if pool.Version == PoolVersionV3 {
+		// slot0 returns (sqrtPriceX96, tick, observationIndex, ...)
+		slot0, err := decodeWords(results[2], 3)
+		if err != nil {
+			return Quote{}, fmt.Errorf("failed to decode %s pool price: %w", symbol, err)
+		}
+		calls = append(calls, eth.EthCall{To: pool.Address, Data: fmt.Sprintf("%s%064x", observationsSelector, slot0[2])})
+	}
+
+	decimalResults, err := c.Config.Caller.BatchEthCall(ctx, calls)
+	if err != nil {
+		return Quote{}, fmt.Errorf("failed to read %s pool token decimals and oracle: %w", symbol, err)
+	}
+	decimals := make([]int64, 2)
+	for i := range decimals {
+		words, err := decodeWords(decimalResults[i], 1)
+		if err != nil {
+			return Quote{}, fmt.Errorf("failed to decode %s pool token decimals: %w", symbol, err)
+		}
+		decimals[i] = words[0].Int64()
+	}
+
+	// price is the value of one token0 in token1
+	var price *big.Float
+	var updatedAt time.Time
+	switch pool.Version {
+	case PoolVersionV3:
+		// sqrtPriceX96 is sqrt(token1/token0) * 2^96
+		slot0, err := decodeWords(results[2], 1)
+		if err != nil {
+			return Quote{}, fmt.Errorf("failed to decode %s pool price: %w", symbol, err)
+		}
+		sqrtPrice := new(big.Float).SetPrec(256).SetInt(slot0[0])
+		sqrtPrice.Quo(sqrtPrice, new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 96)))
+		price = new(big.Float).Mul(sqrtPrice, sqrtPrice)
+
+		// observations returns (blockTimestamp, tickCumulative, secondsPerLiquidityCumulativeX128, initialized)
+		observation, err := decodeWords(decimalResults[2], 1)
+		if err != nil {
+			return Quote{}, fmt.Errorf("failed to decode %s pool observation: %w", symbol, err)
+		}
+		updatedAt = time.Unix(observation[0].Int64(), 0)
+	default:
+		// getReserves returns (reserve0, reserve1, blockTimestampLast)
+		reserves, err := decodeWords(results[2], 3)
+		if err != nil {
+			return Quote{}, fmt.Errorf("failed to decode %s pool reserves: %w", symbol, err)
+		}
+		if reserves[0].Sign() == 0 || reserves[1].Sign() == 0 {
+			return Quote{}, fmt.Errorf("%s pool %s has no reserves", symbol, pool.Address)
+		}
+		price = new(big.Float).SetPrec(256).Quo(new(big.Float).SetInt(reserves[1]), new(big.Float).SetInt(reserves[0]))
+		updatedAt = time.Unix(reserves[2].Int64(), 0)
+	}
+
+	// Adjust the raw price for the token decimals
+	decimalAdjustment := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(abs(decimals[0]-decimals[1])), nil))
+	if decimals[0] > decimals[1] {
+		price.Mul(price, decimalAdjustment)
+	} else {
+		price.Quo(price, decimalAdjustment)
+	}
+	if price.Sign() == 0 {
+		return Quote{}, fmt.Errorf("%s pool %s returned a zero price", symbol, pool.Address)
+	}
+	if !isToken0 {
+		price.Quo(big.NewFloat(1), price)
+	}
+	quotePrice, _ := price.Float64()
+
+	if pool.Quote == usd {
+		return Quote{Price: quotePrice, UpdatedAt: updatedAt}, nil
+	}
+
+	quoteUSD, ok := usdQuotes[pool.Quote]
+	if !ok {
+		return Quote{}, fmt.Errorf("no Chainlink feed known for %s pool quote %s", symbol, pool.Quote)
+	}
+	return Quote{
+		Price:     quotePrice * quoteUSD.Price,
+		UpdatedAt: oldest(updatedAt, quoteUSD.UpdatedAt),
+	}, nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
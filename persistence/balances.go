@@ -0,0 +1,46 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const lastBalancesPrefix = "LAST-BALANCES-"
+
+// LastBalances are the last balances fetched from a balance source, such as "eth" or "pokt".
+type LastBalances struct {
+	Source   string
+	Time     time.Time
+	Balances map[string]float64
+}
+
+// WriteLastBalances replaces the stored last balances of a source. They are
+// stored without a TTL so that they are available however long a source is down.
+func (p *Persistence) WriteLastBalances(balances LastBalances) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(balances); err != nil {
+		return err
+	}
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(lastBalancesPrefix+balances.Source), buf.Bytes())
+	})
+}
+
+// GetLastBalances returns the last balances of a source, or badger.ErrKeyNotFound if none are stored.
+func (p *Persistence) GetLastBalances(source string) (LastBalances, error) {
+	var balances LastBalances
+	err := p.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(lastBalancesPrefix + source))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return gob.NewDecoder(bytes.NewReader(val)).Decode(&balances)
+		})
+	})
+	return balances, err
+}
@@ -0,0 +1,51 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/commoddity/bank-informer/client"
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const breakerStatePrefix = "BREAKER-"
+
+// WriteBreakerState replaces the stored state of an upstream's circuit breaker.
+// It is stored without a TTL so that an open breaker stays open across runs.
+func (p *Persistence) WriteBreakerState(state client.BreakerState) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return err
+	}
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(breakerStatePrefix+state.Upstream), buf.Bytes())
+	})
+}
+
+// GetBreakerStates returns the stored circuit breaker state of each upstream.
+func (p *Persistence) GetBreakerStates() (map[string]client.BreakerState, error) {
+	states := make(map[string]client.BreakerState)
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(breakerStatePrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var state client.BreakerState
+				if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&state); err != nil {
+					return err
+				}
+				states[state.Upstream] = state
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return states, err
+}
@@ -0,0 +1,68 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const (
+	creditUsagePrefix = "CREDITS-"
+	// Set a TTL of 32 days for API credit usage so that a full month can be summed
+	creditTTL = 32 * 24 * time.Hour
+)
+
+// CreditUsage is the number of API credits used by a single call to a provider.
+type CreditUsage struct {
+	Provider string
+	Endpoint string
+	Time     time.Time
+	Credits  int
+}
+
+// WriteCreditUsage stores the credits used by an API call.
+func (p *Persistence) WriteCreditUsage(usage CreditUsage) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(usage); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%s-%s-%s", creditUsagePrefix, usage.Provider, usage.Time.UTC().Format(keyTimeFormat), usage.Endpoint)
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry([]byte(key), buf.Bytes()).WithTTL(creditTTL)
+		return txn.SetEntry(e)
+	})
+}
+
+// GetCreditUsage returns the total credits used by the provider since the given time.
+func (p *Persistence) GetCreditUsage(provider string, since time.Time) (int, error) {
+	var total int
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(creditUsagePrefix + provider + "-")
+		start := []byte(string(prefix) + since.UTC().Format(keyTimeFormat))
+		for it.Seek(start); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var usage CreditUsage
+				if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&usage); err != nil {
+					return err
+				}
+				total += usage.Credits
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return total, err
+}
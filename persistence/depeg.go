@@ -0,0 +1,64 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const depegEventPrefix = "DEPEG-"
+
+// DepegEvent is a stablecoin price that deviated from its peg by more than the
+// configured threshold. Depeg events are stored without a TTL for later review.
+type DepegEvent struct {
+	Symbol       string
+	Peg          string
+	Time         time.Time
+	Price        float64
+	DeviationBps float64
+}
+
+func (p *Persistence) WriteDepegEvent(event DepegEvent) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(event); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%s-%s", depegEventPrefix, event.Time.UTC().Format(time.RFC3339), event.Symbol)
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), buf.Bytes())
+	})
+}
+
+// GetDepegEvents returns all stored depeg events since the given time, oldest first.
+func (p *Persistence) GetDepegEvents(since time.Time) ([]DepegEvent, error) {
+	var events []DepegEvent
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(depegEventPrefix)
+		start := []byte(depegEventPrefix + since.UTC().Format(time.RFC3339))
+		for it.Seek(start); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var event DepegEvent
+				if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&event); err != nil {
+					return err
+				}
+				events = append(events, event)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return events, err
+}
@@ -0,0 +1,109 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const (
+	incomeEventPrefix = "INCOME-POKT-"
+	incomeHeightKey   = "INCOME-POKT-HEIGHT"
+)
+
+// IncomeEvent is POKT income received by a tracked address, valued in fiat
+// at the time it was received. Income events are stored without a TTL so that
+// monthly and yearly totals can be reported.
+type IncomeEvent struct {
+	Address    string
+	Kind       string
+	Height     int64
+	Time       time.Time
+	Amount     float64
+	Fiat       string
+	FiatValue  float64
+	FiatAmount float64
+	// Estimated is set if no price was found near the time the income was
+	// received, and it was valued at the price when it was found instead.
+	Estimated bool
+}
+
+func (p *Persistence) WriteIncomeEvent(event IncomeEvent) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(event); err != nil {
+		return err
+	}
+
+	// Zero-pad the height so that events are iterated in height order
+	key := fmt.Sprintf("%s%020d-%s-%s", incomeEventPrefix, event.Height, event.Address, event.Kind)
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), buf.Bytes())
+	})
+}
+
+// GetIncomeEvents returns all stored income events received at or after since.
+func (p *Persistence) GetIncomeEvents(since time.Time) ([]IncomeEvent, error) {
+	var events []IncomeEvent
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(incomeEventPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if string(item.Key()) == incomeHeightKey {
+				continue
+			}
+
+			err := item.Value(func(val []byte) error {
+				var event IncomeEvent
+				if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&event); err != nil {
+					return fmt.Errorf("Error deserializing income event for key %s: %v", item.Key(), err)
+				}
+				if !event.Time.Before(since) {
+					events = append(events, event)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return events, err
+}
+
+// GetLastIncomeHeight returns the last height searched for income events,
+// or 0 if income events have never been searched.
+func (p *Persistence) GetLastIncomeHeight() (int64, error) {
+	var height int64
+	err := p.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(incomeHeightKey))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			height, err = strconv.ParseInt(string(val), 10, 64)
+			return err
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	return height, err
+}
+
+func (p *Persistence) WriteLastIncomeHeight(height int64) error {
+	return p.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(incomeHeightKey), []byte(strconv.FormatInt(height, 10)))
+	})
+}
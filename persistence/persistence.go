@@ -7,12 +7,21 @@ import (
 	"log"
 	"time"
 
+	"github.com/commoddity/bank-informer/client"
 	"github.com/commoddity/bank-informer/config"
 	badger "github.com/dgraph-io/badger/v3"
 )
 
-// Set a TTL of 72 hours for all data
-const ttl = 72 * time.Hour
+const (
+	// Set a TTL of 72 hours for all crypto values data
+	ttl = 72 * time.Hour
+	// Set a TTL of 30 days for data kept for later review
+	reviewTTL = 30 * 24 * time.Hour
+
+	dateFormat = "2006-01-02"
+	// Fixed-width UTC timestamps sort lexically in keys
+	keyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+)
 
 type (
 	Persistence struct {
@@ -21,9 +30,40 @@ type (
 	IPersistence interface {
 		Close() error
 
+		WriteSnapshot(snapshot Snapshot) error
+		GetSnapshots(from, to time.Time) ([]Snapshot, error)
 		GetAverageCryptoValues(key string) (CryptoValues, error)
-		WriteCryptoValues(key string, value CryptoValues) error
+		ReplaceCryptoValues(key string, value CryptoValues) error
+		MigrateDailyValues() (int, error)
 		ClearOldEntries() error
+
+		GetIncomeEvents(since time.Time) ([]IncomeEvent, error)
+		WriteIncomeEvent(event IncomeEvent) error
+		GetLastIncomeHeight() (int64, error)
+		WriteLastIncomeHeight(height int64) error
+
+		WriteProviderQuotes(provider, fiat string, quotes map[string]float64, t time.Time) error
+		GetProviderQuotes(since time.Time) ([]ProviderQuotes, error)
+
+		WriteExchangeRates(rates CachedExchangeRates) error
+		GetExchangeRates() (CachedExchangeRates, error)
+		WriteFXRates(rates FXRates) error
+		GetFXRates() (FXRates, error)
+
+		WriteDepegEvent(event DepegEvent) error
+		GetDepegEvents(since time.Time) ([]DepegEvent, error)
+
+		WriteLastBalances(balances LastBalances) error
+		GetLastBalances(source string) (LastBalances, error)
+
+		WriteBreakerState(state client.BreakerState) error
+		GetBreakerStates() (map[string]client.BreakerState, error)
+
+		WriteUpstreamStats(stats UpstreamStats) error
+		GetUpstreamStats(since time.Time) ([]UpstreamStats, error)
+
+		WriteCreditUsage(usage CreditUsage) error
+		GetCreditUsage(provider string, since time.Time) (int, error)
 	}
 )
 
@@ -39,6 +79,19 @@ func NewPersistence() *Persistence {
 	return &Persistence{DB: db}
 }
 
+// NewInMemoryPersistence opens an empty database that is only kept in memory.
+func NewInMemoryPersistence() (*Persistence, error) {
+	opts := badger.DefaultOptions("").WithInMemory(true)
+	opts.Logger = nil
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error opening in-memory badger db: %w", err)
+	}
+
+	return &Persistence{DB: db}, nil
+}
+
 func (p *Persistence) Close() error {
 	return p.DB.Close()
 }
@@ -47,80 +100,8 @@ type CryptoValues struct {
 	CryptoBalance float64 `json:"cryptoBalance"`
 	FiatValue     float64 `json:"fiatValue"`
 	FiatBalance   float64 `json:"fiatBalance"`
-}
-
-func (p *Persistence) GetAverageCryptoValues(key string) (CryptoValues, error) {
-	var result CryptoValues
-	err := p.DB.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(key))
-		if err != nil {
-			// Handle the case where the key does not exist
-			return err
-		}
-
-		return item.Value(func(val []byte) error {
-			cryptoValues, err := deserializeCryptoValuesSlice(val)
-			if err != nil {
-				return err
-			}
-
-			var sumCryptoBalance, sumFiatValue, sumFiatBalance float64
-			for _, cv := range cryptoValues {
-				sumCryptoBalance += cv.CryptoBalance
-				sumFiatValue += cv.FiatValue
-				sumFiatBalance += cv.FiatBalance
-			}
-			result = CryptoValues{
-				CryptoBalance: sumCryptoBalance / float64(len(cryptoValues)),
-				FiatValue:     sumFiatValue / float64(len(cryptoValues)),
-				FiatBalance:   sumFiatBalance / float64(len(cryptoValues)),
-			}
-			return nil
-		})
-	})
-
-	return result, err
-}
-
-func (p *Persistence) WriteCryptoValues(key string, value CryptoValues) error {
-	return p.DB.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(key))
-		var values []CryptoValues
-
-		if err == nil {
-			// Key exists, retrieve and deserialize current data
-			err = item.Value(func(val []byte) error {
-				values, err = deserializeCryptoValuesSlice(val)
-				return err
-			})
-			if err != nil {
-				return err
-			}
-		}
-
-		// Append new value
-		values = append(values, value)
-
-		// Serialize updated slice and write back to DB
-		data, err := serializeCryptoValuesSlice(values)
-		if err != nil {
-			return err
-		}
-
-		// Use SetEntry to write data with TTL
-		e := badger.NewEntry([]byte(key), data).WithTTL(ttl)
-		return txn.SetEntry(e)
-	})
-}
-
-func serializeCryptoValuesSlice(values []CryptoValues) ([]byte, error) {
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(values)
-	if err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+	// Backfilled is set on values filled in for days that had no data
+	Backfilled bool `json:"backfilled"`
 }
 
 func deserializeCryptoValuesSlice(data []byte) ([]CryptoValues, error) {
@@ -134,56 +115,13 @@ func deserializeCryptoValuesSlice(data []byte) ([]CryptoValues, error) {
 	return values, nil
 }
 
-type KeyValue struct {
-	Key    string       `json:"key"`
-	Values CryptoValues `json:"values"`
-}
-
-func (p *Persistence) ReadAll() (map[string]CryptoValues, error) {
-	averages := make(map[string]CryptoValues)
-
-	err := p.DB.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		it := txn.NewIterator(opts)
-		defer it.Close()
-
-		for it.Rewind(); it.Valid(); it.Next() {
-			item := it.Item()
-			key := item.Key()
-			err := item.Value(func(val []byte) error {
-				cryptoValues, err := deserializeCryptoValuesSlice(val)
-				if err != nil {
-					return fmt.Errorf("Error deserializing data for key %s: %v", key, err)
-				}
-
-				var sumCryptoBalance, sumFiatValue, sumFiatBalance float64
-				for _, cv := range cryptoValues {
-					sumCryptoBalance += cv.CryptoBalance
-					sumFiatValue += cv.FiatValue
-					sumFiatBalance += cv.FiatBalance
-				}
-				avgCryptoBalance := sumCryptoBalance / float64(len(cryptoValues))
-				avgFiatValue := sumFiatValue / float64(len(cryptoValues))
-				avgFiatBalance := sumFiatBalance / float64(len(cryptoValues))
-
-				avgValues := CryptoValues{
-					CryptoBalance: avgCryptoBalance,
-					FiatValue:     avgFiatValue,
-					FiatBalance:   avgFiatBalance,
-				}
-
-				averages[string(key)] = avgValues
-
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-
-	return averages, err
+// isCryptoValuesKey reports whether key is of the form "<SYMBOL>-<YYYY-MM-DD>".
+func isCryptoValuesKey(key string) bool {
+	if len(key) < len(dateFormat)+2 {
+		return false
+	}
+	_, err := time.Parse(dateFormat, key[len(key)-len(dateFormat):])
+	return err == nil
 }
 
 func (p *Persistence) ClearOldEntries() error {
@@ -194,6 +132,10 @@ func (p *Persistence) ClearOldEntries() error {
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
+			// Entries written without a TTL never expire
+			if item.ExpiresAt() == 0 {
+				continue
+			}
 			expiration := time.Unix(int64(item.ExpiresAt()), 0)
 			if time.Since(expiration) > ttl {
 				err := txn.Delete(item.Key())
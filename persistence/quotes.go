@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const providerQuotesPrefix = "QUOTES-"
+
+// ProviderQuotes are the prices returned by a single price provider in a fiat currency.
+type ProviderQuotes struct {
+	Provider string
+	Fiat     string
+	Time     time.Time
+	Quotes   map[string]float64
+}
+
+// WriteProviderQuotes stores the quotes of a price provider for later review.
+func (p *Persistence) WriteProviderQuotes(provider, fiat string, quotes map[string]float64, t time.Time) error {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(ProviderQuotes{
+		Provider: provider,
+		Fiat:     fiat,
+		Time:     t,
+		Quotes:   quotes,
+	})
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%s-%s-%s", providerQuotesPrefix, t.UTC().Format(time.RFC3339), provider, fiat)
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry([]byte(key), buf.Bytes()).WithTTL(reviewTTL)
+		return txn.SetEntry(e)
+	})
+}
+
+// GetProviderQuotes returns all stored provider quotes since the given time, oldest first.
+func (p *Persistence) GetProviderQuotes(since time.Time) ([]ProviderQuotes, error) {
+	var allQuotes []ProviderQuotes
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(providerQuotesPrefix)
+		start := []byte(providerQuotesPrefix + since.UTC().Format(time.RFC3339))
+		for it.Seek(start); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var quotes ProviderQuotes
+				if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&quotes); err != nil {
+					return err
+				}
+				allQuotes = append(allQuotes, quotes)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return allQuotes, err
+}
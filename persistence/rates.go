@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const exchangeRatesKey = "EXCHANGE-RATES"
+
+// CachedExchangeRates are the last fetched exchange rates, along with the symbols
+// they were requested for and when they were fetched.
+type CachedExchangeRates struct {
+	Time          time.Time
+	Symbols       []string
+	ExchangeRates map[string]map[string]float64
+}
+
+// WriteExchangeRates replaces the cached exchange rates. They are stored
+// without a TTL so that they are always available in offline mode.
+func (p *Persistence) WriteExchangeRates(rates CachedExchangeRates) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rates); err != nil {
+		return err
+	}
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(exchangeRatesKey), buf.Bytes())
+	})
+}
+
+// GetExchangeRates returns the cached exchange rates, or badger.ErrKeyNotFound if none are cached.
+func (p *Persistence) GetExchangeRates() (CachedExchangeRates, error) {
+	var rates CachedExchangeRates
+	err := p.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(exchangeRatesKey))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return gob.NewDecoder(bytes.NewReader(val)).Decode(&rates)
+		})
+	})
+	return rates, err
+}
+
+const fxRatesKey = "FX-RATES"
+
+// FXRates are the last fetched fiat-to-fiat exchange rates, as the value
+// of one unit of Base in each currency.
+type FXRates struct {
+	Provider string
+	Time     time.Time
+	Base     string
+	Rates    map[string]float64
+}
+
+// WriteFXRates replaces the stored FX rates.
+func (p *Persistence) WriteFXRates(rates FXRates) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rates); err != nil {
+		return err
+	}
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(fxRatesKey), buf.Bytes())
+	})
+}
+
+// GetFXRates returns the stored FX rates, or badger.ErrKeyNotFound if none are stored.
+func (p *Persistence) GetFXRates() (FXRates, error) {
+	var rates FXRates
+	err := p.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fxRatesKey))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return gob.NewDecoder(bytes.NewReader(val)).Decode(&rates)
+		})
+	})
+	return rates, err
+}
@@ -0,0 +1,63 @@
+package persistence
+
+import (
+	"fmt"
+	"time"
+)
+
+// RunSnapshot collects the status and block heights of the sources of a run
+// for the snapshot stored at the end of the run.
+type RunSnapshot struct {
+	p       *Persistence
+	sources map[string]SourceStatus
+	heights map[string]int64
+}
+
+// NewRunSnapshot returns an empty snapshot of a run, stored in p when it is written.
+func (p *Persistence) NewRunSnapshot() *RunSnapshot {
+	return &RunSnapshot{
+		p:       p,
+		sources: make(map[string]SourceStatus),
+		heights: make(map[string]int64),
+	}
+}
+
+func (s *RunSnapshot) Fetched(source string) {
+	s.sources[source] = SourceStatus{Status: SourceFetched}
+}
+
+func (s *RunSnapshot) LastKnown(source string, t time.Time) {
+	s.sources[source] = SourceStatus{Status: SourceLastKnown, Time: t}
+}
+
+func (s *RunSnapshot) Cached(source string, t time.Time) {
+	s.sources[source] = SourceStatus{Status: SourceCached, Time: t}
+}
+
+func (s *RunSnapshot) Skipped(source string, err error) {
+	s.sources[source] = SourceStatus{Status: SourceSkipped, Err: err.Error()}
+}
+
+// Height records the block height of a source, if it is known.
+func (s *RunSnapshot) Height(source string, height int64) {
+	if height > 0 {
+		s.heights[source] = height
+	}
+}
+
+// Write stores the snapshot with the values of each asset and the exchange rates of the run.
+func (s *RunSnapshot) Write(fiat string, assets map[string]CryptoValues, prices map[string]map[string]float64) error {
+	err := s.p.WriteSnapshot(Snapshot{
+		RunID:        NewRunID(),
+		Time:         time.Now(),
+		Fiat:         fiat,
+		Assets:       assets,
+		Prices:       prices,
+		BlockHeights: s.heights,
+		Sources:      s.sources,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store the snapshot of the run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package persistence
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const snapshotPrefix = "SNAPSHOT-"
+
+// Source statuses of a snapshot.
+const (
+	// SourceFetched means the values were fetched during the run.
+	SourceFetched = "fetched"
+	// SourceLastKnown means the source was down and its last known values were used.
+	SourceLastKnown = "last-known"
+	// SourceCached means cached values were used instead of fetching new ones.
+	SourceCached = "cached"
+	// SourceSkipped means the source was down and its values are missing.
+	SourceSkipped = "skipped"
+)
+
+// Snapshot is the state of all assets at the time of a single run.
+type Snapshot struct {
+	RunID string
+	Time  time.Time
+	// Fiat is the currency of the fiat values of Assets.
+	Fiat string
+	// Assets are the values of each symbol and position, e.g. "ETH" or "POKT-EXCHANGE".
+	Assets map[string]CryptoValues
+	// Prices are the exchange rates of each symbol in each fiat currency.
+	Prices map[string]map[string]float64
+	// BlockHeights are the heights the values of each source were fetched at, if known.
+	BlockHeights map[string]int64
+	// Sources are the status of each source of balances and prices.
+	Sources map[string]SourceStatus
+	// Backfilled is set on snapshots filled in for days that had no data.
+	Backfilled bool
+	// Migrated is set on snapshots migrated from daily values, whose run
+	// times are unknown and are instead spaced one second apart from the
+	// start of their day.
+	Migrated bool
+}
+
+// SourceStatus is the status of a source of balances or prices during a run.
+type SourceStatus struct {
+	Status string
+	// Time is the time its values were fetched at, if they were not fetched during the run.
+	Time time.Time
+	Err  string
+}
+
+// NewRunID returns a random ID for a run.
+func NewRunID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func snapshotKey(snapshot Snapshot) []byte {
+	return []byte(fmt.Sprintf("%s%s-%s", snapshotPrefix, snapshot.Time.UTC().Format(keyTimeFormat), snapshot.RunID))
+}
+
+// WriteSnapshot stores the snapshot of a run.
+func (p *Persistence) WriteSnapshot(snapshot Snapshot) error {
+	data, err := encodeSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry(snapshotKey(snapshot), data).WithTTL(ttl)
+		return txn.SetEntry(e)
+	})
+}
+
+// GetSnapshots returns the stored snapshots from the from time up to but
+// excluding the to time, oldest first.
+func (p *Persistence) GetSnapshots(from, to time.Time) ([]Snapshot, error) {
+	var snapshots []Snapshot
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		var err error
+		snapshots, err = getSnapshots(txn, from, to)
+		return err
+	})
+
+	return snapshots, err
+}
+
+func getSnapshots(txn *badger.Txn, from, to time.Time) ([]Snapshot, error) {
+	var snapshots []Snapshot
+
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	prefix := []byte(snapshotPrefix)
+	start := []byte(snapshotPrefix + from.UTC().Format(keyTimeFormat))
+	end := []byte(snapshotPrefix + to.UTC().Format(keyTimeFormat))
+	for it.Seek(start); it.ValidForPrefix(prefix) && bytes.Compare(it.Item().Key(), end) < 0; it.Next() {
+		err := it.Item().Value(func(val []byte) error {
+			snapshot, err := decodeSnapshot(val)
+			if err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshots, nil
+}
+
+// GetAverageCryptoValues returns the average values of an asset on a day from
+// the snapshots of that day, where key is of the form "<ASSET>-<YYYY-MM-DD>".
+// It returns badger.ErrKeyNotFound if no snapshot of the day has the asset.
+func (p *Persistence) GetAverageCryptoValues(key string) (CryptoValues, error) {
+	asset, day, err := parseCryptoValuesKey(key)
+	if err != nil {
+		return CryptoValues{}, err
+	}
+
+	snapshots, err := p.GetSnapshots(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return CryptoValues{}, err
+	}
+
+	var values []CryptoValues
+	for _, snapshot := range snapshots {
+		if value, ok := snapshot.Assets[asset]; ok {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return CryptoValues{}, badger.ErrKeyNotFound
+	}
+
+	return averageCryptoValues(values), nil
+}
+
+// ReplaceCryptoValues replaces all values of an asset on a day with a single
+// value, stored in a snapshot at the end of the day. The key is of the form
+// "<ASSET>-<YYYY-MM-DD>".
+func (p *Persistence) ReplaceCryptoValues(key string, value CryptoValues) error {
+	asset, day, err := parseCryptoValuesKey(key)
+	if err != nil {
+		return err
+	}
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		snapshots, err := getSnapshots(txn, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+
+		// Remove the asset from the day's snapshots, keeping their expiry
+		for _, snapshot := range snapshots {
+			if _, ok := snapshot.Assets[asset]; !ok {
+				continue
+			}
+			item, err := txn.Get(snapshotKey(snapshot))
+			if err != nil {
+				return err
+			}
+			delete(snapshot.Assets, asset)
+			if len(snapshot.Assets) == 0 {
+				if err := txn.Delete(item.KeyCopy(nil)); err != nil {
+					return err
+				}
+				continue
+			}
+			data, err := encodeSnapshot(snapshot)
+			if err != nil {
+				return err
+			}
+			e := badger.NewEntry(item.KeyCopy(nil), data)
+			e.ExpiresAt = item.ExpiresAt()
+			if err := txn.SetEntry(e); err != nil {
+				return err
+			}
+		}
+
+		snapshot := Snapshot{
+			RunID:      NewRunID(),
+			Time:       day.AddDate(0, 0, 1).Add(-time.Second),
+			Assets:     map[string]CryptoValues{asset: value},
+			Backfilled: value.Backfilled,
+		}
+		data, err := encodeSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+		e := badger.NewEntry(snapshotKey(snapshot), data).WithTTL(ttl)
+		return txn.SetEntry(e)
+	})
+}
+
+// ReadAll returns the average values of each asset on each day, keyed by "<ASSET>-<YYYY-MM-DD>".
+func (p *Persistence) ReadAll() (map[string]CryptoValues, error) {
+	snapshots, err := p.GetSnapshots(time.Time{}, time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]CryptoValues)
+	for _, snapshot := range snapshots {
+		date := snapshot.Time.Local().Format(dateFormat)
+		for asset, value := range snapshot.Assets {
+			key := fmt.Sprintf("%s-%s", asset, date)
+			values[key] = append(values[key], value)
+		}
+	}
+
+	averages := make(map[string]CryptoValues)
+	for key, keyValues := range values {
+		averages[key] = averageCryptoValues(keyValues)
+	}
+	return averages, nil
+}
+
+// MigrateDailyValues converts the values stored under daily "<ASSET>-<YYYY-MM-DD>"
+// keys, which have no run times, to snapshots and deletes the daily keys. The
+// n-th values of each asset on a day are taken to be from the same run. It returns
+// the number of daily keys migrated.
+func (p *Persistence) MigrateDailyValues() (int, error) {
+	type dailyValues struct {
+		assets    map[string][]CryptoValues
+		expiresAt uint64
+	}
+	days := make(map[string]*dailyValues)
+	var keys [][]byte
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := string(item.Key())
+			if !isCryptoValuesKey(key) {
+				continue
+			}
+			asset, date := key[:len(key)-len(dateFormat)-1], key[len(key)-len(dateFormat):]
+
+			err := item.Value(func(val []byte) error {
+				values, err := deserializeCryptoValuesSlice(val)
+				if err != nil {
+					return fmt.Errorf("failed to decode %s: %w", key, err)
+				}
+				if days[date] == nil {
+					days[date] = &dailyValues{assets: make(map[string][]CryptoValues)}
+				}
+				days[date].assets[asset] = values
+				days[date].expiresAt = max(days[date].expiresAt, item.ExpiresAt())
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			keys = append(keys, item.KeyCopy(nil))
+		}
+		return nil
+	})
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+
+	err = p.DB.Update(func(txn *badger.Txn) error {
+		for date, day := range days {
+			start, err := time.ParseInLocation(dateFormat, date, time.Local)
+			if err != nil {
+				return err
+			}
+
+			runs := 0
+			for _, values := range day.assets {
+				runs = max(runs, len(values))
+			}
+
+			for run := 0; run < runs; run++ {
+				snapshot := Snapshot{
+					RunID:    fmt.Sprintf("migrated-%s-%d", date, run+1),
+					Time:     start.Add(time.Duration(run) * time.Second),
+					Assets:   make(map[string]CryptoValues),
+					Migrated: true,
+				}
+				for asset, values := range day.assets {
+					if run < len(values) {
+						snapshot.Assets[asset] = values[run]
+						snapshot.Backfilled = snapshot.Backfilled || values[run].Backfilled
+					}
+				}
+
+				data, err := encodeSnapshot(snapshot)
+				if err != nil {
+					return err
+				}
+				e := badger.NewEntry(snapshotKey(snapshot), data)
+				e.ExpiresAt = day.expiresAt
+				if err := txn.SetEntry(e); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(keys), nil
+}
+
+// parseCryptoValuesKey splits a key of the form "<ASSET>-<YYYY-MM-DD>" into the
+// asset and the start of the day in local time.
+func parseCryptoValuesKey(key string) (string, time.Time, error) {
+	if !isCryptoValuesKey(key) {
+		return "", time.Time{}, fmt.Errorf("invalid crypto values key: %s", key)
+	}
+	day, err := time.ParseInLocation(dateFormat, key[len(key)-len(dateFormat):], time.Local)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return key[:len(key)-len(dateFormat)-1], day, nil
+}
+
+func averageCryptoValues(values []CryptoValues) CryptoValues {
+	var sum CryptoValues
+	for _, value := range values {
+		sum.CryptoBalance += value.CryptoBalance
+		sum.FiatValue += value.FiatValue
+		sum.FiatBalance += value.FiatBalance
+		sum.Backfilled = sum.Backfilled || value.Backfilled
+	}
+	n := float64(len(values))
+	return CryptoValues{
+		CryptoBalance: sum.CryptoBalance / n,
+		FiatValue:     sum.FiatValue / n,
+		FiatBalance:   sum.FiatBalance / n,
+		Backfilled:    sum.Backfilled,
+	}
+}
+
+func encodeSnapshot(snapshot Snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSnapshot(data []byte) (Snapshot, error) {
+	var snapshot Snapshot
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot)
+	return snapshot, err
+}
@@ -0,0 +1,224 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+func newTestPersistence(t *testing.T) *Persistence {
+	t.Helper()
+
+	opts := badger.DefaultOptions(t.TempDir())
+	opts.Logger = nil
+	db, err := badger.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &Persistence{DB: db}
+}
+
+// writeDailyValues writes values under a daily key as earlier versions did.
+func writeDailyValues(t *testing.T, p *Persistence, key string, values []CryptoValues, expiresAt uint64) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		t.Fatal(err)
+	}
+	err := p.DB.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry([]byte(key), buf.Bytes())
+		e.ExpiresAt = expiresAt
+		return txn.SetEntry(e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// snapshotExpiries returns the expiry of each stored snapshot by its run ID.
+func snapshotExpiries(t *testing.T, p *Persistence) map[string]uint64 {
+	t.Helper()
+
+	expiries := make(map[string]uint64)
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(snapshotPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := string(it.Item().Key())
+			runID := key[len(snapshotPrefix)+len(keyTimeFormat)+1:]
+			expiries[runID] = it.Item().ExpiresAt()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expiries
+}
+
+func TestMigrateDailyValues(t *testing.T) {
+	p := newTestPersistence(t)
+
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.Local)
+	date := day.Format(dateFormat)
+	backfilledDay := day.AddDate(0, 0, -1)
+	backfilledDate := backfilledDay.Format(dateFormat)
+
+	ethExpiry := uint64(now.Add(24 * time.Hour).Unix())
+	usdcExpiry := uint64(now.Add(48 * time.Hour).Unix())
+	backfillExpiry := uint64(now.Add(12 * time.Hour).Unix())
+
+	eth := []CryptoValues{
+		{CryptoBalance: 1, FiatValue: 2000, FiatBalance: 2000},
+		{CryptoBalance: 1, FiatValue: 2100, FiatBalance: 2100},
+		{CryptoBalance: 2, FiatValue: 2200, FiatBalance: 4400},
+	}
+	// USDC was added to crypto_values after the first run of the day
+	usdc := []CryptoValues{
+		{CryptoBalance: 50, FiatValue: 1, FiatBalance: 50},
+	}
+	// Written by a POKT balance backfill, which replaced the day's values with one value
+	backfilledPOKT := []CryptoValues{
+		{CryptoBalance: 1000, FiatValue: 0.05, FiatBalance: 50, Backfilled: true},
+	}
+
+	writeDailyValues(t, p, "ETH-"+date, eth, ethExpiry)
+	writeDailyValues(t, p, "USDC-"+date, usdc, usdcExpiry)
+	writeDailyValues(t, p, "POKT-"+backfilledDate, backfilledPOKT, backfillExpiry)
+	if err := p.WriteLastIncomeHeight(42); err != nil {
+		t.Fatal(err)
+	}
+
+	// Averages before the migration
+	wantAverages := map[string]CryptoValues{
+		"ETH-" + date:            averageCryptoValues(eth),
+		"USDC-" + date:           averageCryptoValues(usdc),
+		"POKT-" + backfilledDate: averageCryptoValues(backfilledPOKT),
+	}
+
+	migrated, err := p.MigrateDailyValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 3 {
+		t.Errorf("migrated %d daily keys, want 3", migrated)
+	}
+
+	// The daily keys are deleted, and other keys are kept
+	for key := range wantAverages {
+		err := p.DB.View(func(txn *badger.Txn) error {
+			_, err := txn.Get([]byte(key))
+			return err
+		})
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			t.Errorf("daily key %s was not deleted: %v", key, err)
+		}
+	}
+	if height, err := p.GetLastIncomeHeight(); err != nil || height != 42 {
+		t.Errorf("income height = %d, %v, want 42", height, err)
+	}
+
+	// The n-th values of each asset on a day are in the n-th run of the day
+	snapshots, err := p.GetSnapshots(time.Time{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 4 {
+		t.Fatalf("got %d snapshots, want 4", len(snapshots))
+	}
+
+	backfilled := snapshots[0]
+	if !backfilled.Time.Equal(backfilledDay) || !backfilled.Migrated || !backfilled.Backfilled {
+		t.Errorf("backfilled snapshot = %+v, want a migrated, backfilled snapshot at %s", backfilled, backfilledDay)
+	}
+	if backfilled.Assets["POKT"] != backfilledPOKT[0] || len(backfilled.Assets) != 1 {
+		t.Errorf("backfilled snapshot assets = %+v, want only POKT %+v", backfilled.Assets, backfilledPOKT[0])
+	}
+
+	for run, snapshot := range snapshots[1:] {
+		wantTime := day.Add(time.Duration(run) * time.Second)
+		if !snapshot.Time.Equal(wantTime) {
+			t.Errorf("run %d time = %s, want %s", run+1, snapshot.Time, wantTime)
+		}
+		if !snapshot.Migrated || snapshot.Backfilled {
+			t.Errorf("run %d is migrated %t and backfilled %t, want only migrated", run+1, snapshot.Migrated, snapshot.Backfilled)
+		}
+		if !strings.HasPrefix(snapshot.RunID, "migrated-"+date) {
+			t.Errorf("run %d ID = %s, want a migrated run ID of %s", run+1, snapshot.RunID, date)
+		}
+		if snapshot.Assets["ETH"] != eth[run] {
+			t.Errorf("run %d ETH = %+v, want %+v", run+1, snapshot.Assets["ETH"], eth[run])
+		}
+
+		// The run times of the daily values are unknown, so USDC is taken to be from the first run
+		wantAssets := 1
+		if run == 0 {
+			wantAssets = 2
+			if snapshot.Assets["USDC"] != usdc[0] {
+				t.Errorf("run 1 USDC = %+v, want %+v", snapshot.Assets["USDC"], usdc[0])
+			}
+		}
+		if len(snapshot.Assets) != wantAssets {
+			t.Errorf("run %d has %d assets, want %d", run+1, len(snapshot.Assets), wantAssets)
+		}
+	}
+
+	// The daily averages are the same as before the migration
+	for key, want := range wantAverages {
+		got, err := p.GetAverageCryptoValues(key)
+		if err != nil {
+			t.Errorf("average of %s: %v", key, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("average of %s = %+v, want %+v", key, got, want)
+		}
+	}
+	all, err := p.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(wantAverages) {
+		t.Errorf("ReadAll returned %d keys, want %d", len(all), len(wantAverages))
+	}
+	for key, want := range wantAverages {
+		if all[key] != want {
+			t.Errorf("ReadAll %s = %+v, want %+v", key, all[key], want)
+		}
+	}
+
+	// The snapshots of a day expire with the last expiring daily key of the day
+	expiries := snapshotExpiries(t, p)
+	if len(expiries) != len(snapshots) {
+		t.Errorf("got %d snapshot expiries, want %d", len(expiries), len(snapshots))
+	}
+	for runID, expiresAt := range expiries {
+		want := usdcExpiry
+		if strings.HasPrefix(runID, "migrated-"+backfilledDate) {
+			want = backfillExpiry
+		}
+		if expiresAt != want {
+			t.Errorf("snapshot %s expires at %d, want %d", runID, expiresAt, want)
+		}
+	}
+
+	// Migrating again finds nothing to migrate
+	migrated, err = p.MigrateDailyValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 0 {
+		t.Errorf("second migration migrated %d daily keys, want 0", migrated)
+	}
+}
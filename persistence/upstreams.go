@@ -0,0 +1,89 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/commoddity/bank-informer/client"
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const upstreamStatsPrefix = "UPSTREAM-"
+
+// UpstreamStats are the totals of the calls to an upstream during a single run.
+type UpstreamStats struct {
+	RunTime      time.Time
+	Upstream     string
+	Calls        int
+	Errors       int
+	Attempts     int
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+	Bytes        int64
+}
+
+// WriteUpstreamSummaries stores the upstream call summaries of the run started at runTime.
+func (p *Persistence) WriteUpstreamSummaries(runTime time.Time, summaries []client.UpstreamSummary) error {
+	for _, summary := range summaries {
+		err := p.WriteUpstreamStats(UpstreamStats{
+			RunTime:      runTime,
+			Upstream:     summary.Upstream,
+			Calls:        summary.Calls,
+			Errors:       summary.Errors,
+			Attempts:     summary.Attempts,
+			TotalLatency: summary.TotalLatency,
+			MaxLatency:   summary.MaxLatency,
+			Bytes:        summary.Bytes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteUpstreamStats stores the upstream totals of a run for later comparison.
+func (p *Persistence) WriteUpstreamStats(stats UpstreamStats) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(stats); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%s-%s", upstreamStatsPrefix, stats.RunTime.UTC().Format(time.RFC3339), stats.Upstream)
+
+	return p.DB.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry([]byte(key), buf.Bytes()).WithTTL(reviewTTL)
+		return txn.SetEntry(e)
+	})
+}
+
+// GetUpstreamStats returns the stored upstream totals of all runs since the given time, oldest first.
+func (p *Persistence) GetUpstreamStats(since time.Time) ([]UpstreamStats, error) {
+	var allStats []UpstreamStats
+
+	err := p.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(upstreamStatsPrefix)
+		start := []byte(upstreamStatsPrefix + since.UTC().Format(time.RFC3339))
+		for it.Seek(start); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var stats UpstreamStats
+				if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&stats); err != nil {
+					return err
+				}
+				allStats = append(allStats, stats)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return allStats, err
+}
@@ -0,0 +1,60 @@
+package pokt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// GetHeightAtTime returns the height of the last block with a block time at or
+// before t, found by binary searching the block times of the available heights.
+func (c *Client) GetHeightAtTime(ctx context.Context, t time.Time) (int64, error) {
+	status, err := cometRPC[statusResult](ctx, c, "status", map[string]any{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	low, err := strconv.ParseInt(status.SyncInfo.EarliestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	high, err := strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	earliestTime, err := c.getBlockTime(ctx, low)
+	if err != nil {
+		return 0, err
+	}
+	if earliestTime.After(t) {
+		return 0, fmt.Errorf("no block available at %s, earliest block %d is at %s", t.Format(time.RFC3339), low, earliestTime.Format(time.RFC3339))
+	}
+
+	// Invariant: the block at low is at or before t
+	for low < high {
+		mid := low + (high-low+1)/2
+
+		blockTime, err := c.getBlockTime(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if blockTime.After(t) {
+			high = mid - 1
+		} else {
+			low = mid
+		}
+	}
+
+	return low, nil
+}
+
+func (c *Client) getBlockTime(ctx context.Context, height int64) (time.Time, error) {
+	header, err := cometRPC[headerResult](ctx, c, "header", map[string]any{"height": strconv.FormatInt(height, 10)})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get header at height %d: %w", height, err)
+	}
+	return header.Header.Time, nil
+}
@@ -0,0 +1,324 @@
+package pokt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const (
+	// Reward settlements are paid out at the end of the block, so they are
+	// found in the block's finalize block events rather than in a transaction.
+	// Only transfers from the tokenomics module are rewards, as stake returned
+	// by supplier unstaking or unbonding is also received at the end of a block.
+	settlementQuery = "transfer.recipient='%s' AND transfer.sender='%s' AND block.height > %d AND block.height <= %d"
+	// Delegator reward claims are found in the events of the withdrawal transaction.
+	claimQuery = "withdraw_rewards.delegator='%s' AND tx.height > %d AND tx.height <= %d"
+
+	searchPageSize = 100
+
+	// tokenomicsModuleAddress is the account of the tokenomics module, which pays
+	// out the rewards of settled relay mining claims.
+	tokenomicsModuleAddress = "pokt14cvnmzrt9cz4qdf5lhs0xn3u0a3gymla9cc6ft"
+
+	RewardKindSettlement = "settlement"
+	RewardKindClaim      = "claim"
+)
+
+// RewardEvent is POKT income received by a tracked address at a given height.
+type RewardEvent struct {
+	Address string
+	Kind    string
+	Height  int64
+	Time    time.Time
+	Amount  float64
+}
+
+type (
+	cometRPCRequest struct {
+		Jsonrpc string         `json:"jsonrpc"`
+		Method  string         `json:"method"`
+		Params  map[string]any `json:"params"`
+		Id      int            `json:"id"`
+	}
+
+	cometRPCResponse[T any] struct {
+		Result T `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    string `json:"data"`
+		} `json:"error,omitempty"`
+	}
+
+	cometEvent struct {
+		Type       string `json:"type"`
+		Attributes []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"attributes"`
+	}
+
+	cometHeader struct {
+		Height string    `json:"height"`
+		Time   time.Time `json:"time"`
+	}
+
+	statusResult struct {
+		SyncInfo struct {
+			LatestBlockHeight   string `json:"latest_block_height"`
+			EarliestBlockHeight string `json:"earliest_block_height"`
+		} `json:"sync_info"`
+	}
+
+	blockSearchResult struct {
+		Blocks []struct {
+			Block struct {
+				Header cometHeader `json:"header"`
+			} `json:"block"`
+		} `json:"blocks"`
+		TotalCount string `json:"total_count"`
+	}
+
+	blockResultsResult struct {
+		FinalizeBlockEvents []cometEvent `json:"finalize_block_events"`
+	}
+
+	txSearchResult struct {
+		Txs []struct {
+			Height   string `json:"height"`
+			TxResult struct {
+				Events []cometEvent `json:"events"`
+			} `json:"tx_result"`
+		} `json:"txs"`
+		TotalCount string `json:"total_count"`
+	}
+
+	headerResult struct {
+		Header cometHeader `json:"header"`
+	}
+)
+
+// GetLatestHeight returns the latest block height of the POKT network.
+func (c *Client) GetLatestHeight(ctx context.Context) (int64, error) {
+	status, err := cometRPC[statusResult](ctx, c, "status", map[string]any{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status: %w", err)
+	}
+	return strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+}
+
+// GetRewardEvents retrieves the reward settlement and claim events for each of the
+// given addresses with a height greater than sinceHeight. It also returns the latest
+// height that was searched, which should be passed as sinceHeight on the next call.
+// If sinceHeight is 0, no events are searched and only the latest height is returned,
+// as searching the whole chain would not finish within a run.
+func (c *Client) GetRewardEvents(ctx context.Context, addresses []string, sinceHeight int64) ([]RewardEvent, int64, error) {
+	latestHeight, err := c.GetLatestHeight(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if sinceHeight == 0 {
+		c.progressChan <- "INCOME"
+		return nil, latestHeight, nil
+	}
+
+	var events []RewardEvent
+	for _, address := range addresses {
+		settlements, err := c.getSettlementEvents(ctx, address, sinceHeight, latestHeight)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get settlement events for %s: %w", address, err)
+		}
+		events = append(events, settlements...)
+
+		claims, err := c.getClaimEvents(ctx, address, sinceHeight, latestHeight)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get claim events for %s: %w", address, err)
+		}
+		events = append(events, claims...)
+	}
+
+	c.progressChan <- "INCOME"
+
+	return events, latestHeight, nil
+}
+
+func (c *Client) getSettlementEvents(ctx context.Context, address string, sinceHeight, latestHeight int64) ([]RewardEvent, error) {
+	var events []RewardEvent
+
+	query := fmt.Sprintf(settlementQuery, address, tokenomicsModuleAddress, sinceHeight, latestHeight)
+	for page := 1; ; page++ {
+		search, err := cometRPC[blockSearchResult](ctx, c, "block_search", searchParams(query, page))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, block := range search.Blocks {
+			header := block.Block.Header
+			results, err := cometRPC[blockResultsResult](ctx, c, "block_results", map[string]any{"height": header.Height})
+			if err != nil {
+				return nil, err
+			}
+
+			amount, err := sumEventAmounts(results.FinalizeBlockEvents, "transfer", map[string]string{
+				"recipient": address,
+				"sender":    tokenomicsModuleAddress,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if amount == 0 {
+				continue
+			}
+
+			height, err := strconv.ParseInt(header.Height, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, RewardEvent{
+				Address: address,
+				Kind:    RewardKindSettlement,
+				Height:  height,
+				Time:    header.Time,
+				Amount:  amount,
+			})
+		}
+
+		if !hasNextPage(page, search.TotalCount) {
+			return events, nil
+		}
+	}
+}
+
+func (c *Client) getClaimEvents(ctx context.Context, address string, sinceHeight, latestHeight int64) ([]RewardEvent, error) {
+	var events []RewardEvent
+
+	query := fmt.Sprintf(claimQuery, address, sinceHeight, latestHeight)
+	for page := 1; ; page++ {
+		search, err := cometRPC[txSearchResult](ctx, c, "tx_search", searchParams(query, page))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range search.Txs {
+			amount, err := sumEventAmounts(tx.TxResult.Events, "withdraw_rewards", map[string]string{"delegator": address})
+			if err != nil {
+				return nil, err
+			}
+			if amount == 0 {
+				continue
+			}
+
+			// The transaction search result does not include the block time
+			header, err := cometRPC[headerResult](ctx, c, "header", map[string]any{"height": tx.Height})
+			if err != nil {
+				return nil, err
+			}
+
+			height, err := strconv.ParseInt(tx.Height, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, RewardEvent{
+				Address: address,
+				Kind:    RewardKindClaim,
+				Height:  height,
+				Time:    header.Header.Time,
+				Amount:  amount,
+			})
+		}
+
+		if !hasNextPage(page, search.TotalCount) {
+			return events, nil
+		}
+	}
+}
+
+// sumEventAmounts sums the upokt "amount" attribute of every event of eventType
+// whose attributes match all of the given attributes, and returns the total in POKT.
+func sumEventAmounts(events []cometEvent, eventType string, attributes map[string]string) (float64, error) {
+	total := new(big.Float)
+
+	for _, event := range events {
+		if event.Type != eventType {
+			continue
+		}
+
+		var eventAmount string
+		matched := 0
+		for _, attribute := range event.Attributes {
+			if attribute.Key == "amount" {
+				eventAmount = attribute.Value
+			} else if value, ok := attributes[attribute.Key]; ok && attribute.Value == value {
+				matched++
+			}
+		}
+		if matched != len(attributes) {
+			continue
+		}
+
+		// Amounts are a comma-separated list of coins, e.g. "1000upokt,5uother"
+		for _, coin := range strings.Split(eventAmount, ",") {
+			if !strings.HasSuffix(coin, "upokt") {
+				continue
+			}
+			amount, err := parseAmount(strings.TrimSuffix(coin, "upokt"))
+			if err != nil {
+				return 0, err
+			}
+			total.Add(total, amount)
+		}
+	}
+
+	return upoktToPOKT(total), nil
+}
+
+func searchParams(query string, page int) map[string]any {
+	return map[string]any{
+		"query":    query,
+		"page":     strconv.Itoa(page),
+		"per_page": strconv.Itoa(searchPageSize),
+		"order_by": "asc",
+	}
+}
+
+func hasNextPage(page int, totalCount string) bool {
+	total, err := strconv.Atoi(totalCount)
+	if err != nil {
+		return false
+	}
+	return page*searchPageSize < total
+}
+
+// cometRPC sends a CometBFT JSON-RPC request for the given method through PATH.
+func cometRPC[T any](ctx context.Context, c *Client, method string, params map[string]any) (T, error) {
+	var result T
+
+	header := c.header()
+	header.Set("Content-Type", "application/json")
+	// CometBFT queries are read-only, so they are safe to retry
+	client.MarkIdempotent(header)
+
+	reqBody, err := json.Marshal(cometRPCRequest{Jsonrpc: "2.0", Method: method, Params: params, Id: 1})
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	resp, err := client.Post[cometRPCResponse[T]](ctx, c.Config.PathApiUrl, header, reqBody, c.httpClient)
+	if err != nil {
+		return result, err
+	}
+	if resp.Error != nil {
+		return result, fmt.Errorf("error for method %s: %s %s", method, resp.Error.Message, resp.Error.Data)
+	}
+
+	return resp.Result, nil
+}
@@ -0,0 +1,77 @@
+package pokt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const (
+	testSupplier = "pokt1supplier"
+	// The accounts of the staking pools and the supplier module, which return stake
+	bondedPoolAddress    = "pokt1fl48vsnmsdzcv85q5d2q4z5ajdha8yu3gakm90"
+	notBondedPoolAddress = "pokt1tygms3xhhs3yv487phx3dw4a95jn7t7lua22nm"
+	supplierModuleAddr   = "pokt1j40dzzmn6cn9kxku7a5tjnud6hv37vesr5ccaa"
+)
+
+func transferEvents(t *testing.T, sender, recipient, amount string) []cometEvent {
+	t.Helper()
+
+	var events []cometEvent
+	err := json.Unmarshal([]byte(`[
+		{"type": "coin_spent", "attributes": [
+			{"key": "spender", "value": "`+sender+`"},
+			{"key": "amount", "value": "`+amount+`"},
+			{"key": "mode", "value": "EndBlock"}]},
+		{"type": "coin_received", "attributes": [
+			{"key": "receiver", "value": "`+recipient+`"},
+			{"key": "amount", "value": "`+amount+`"},
+			{"key": "mode", "value": "EndBlock"}]},
+		{"type": "transfer", "attributes": [
+			{"key": "recipient", "value": "`+recipient+`"},
+			{"key": "sender", "value": "`+sender+`"},
+			{"key": "amount", "value": "`+amount+`"},
+			{"key": "mode", "value": "EndBlock"}]}
+	]`), &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func settlementAmount(t *testing.T, events []cometEvent) float64 {
+	t.Helper()
+
+	amount, err := sumEventAmounts(events, "transfer", map[string]string{
+		"recipient": testSupplier,
+		"sender":    tokenomicsModuleAddress,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amount
+}
+
+func TestSettlementAmountCountsTokenomicsTransfers(t *testing.T) {
+	events := transferEvents(t, tokenomicsModuleAddress, testSupplier, "1500000upokt")
+	events = append(events, transferEvents(t, tokenomicsModuleAddress, "pokt1other", "7000000upokt")...)
+
+	if amount := settlementAmount(t, events); amount != 1.5 {
+		t.Errorf("got %v POKT, want 1.5", amount)
+	}
+}
+
+func TestSettlementAmountIgnoresReturnedStake(t *testing.T) {
+	tests := map[string][]cometEvent{
+		"completed unbonding":  transferEvents(t, notBondedPoolAddress, testSupplier, "25000000000upokt"),
+		"bonded pool transfer": transferEvents(t, bondedPoolAddress, testSupplier, "25000000000upokt"),
+		"supplier unstake":     transferEvents(t, supplierModuleAddr, testSupplier, "60000000000upokt"),
+	}
+
+	for name, events := range tests {
+		t.Run(name, func(t *testing.T) {
+			if amount := settlementAmount(t, events); amount != 0 {
+				t.Errorf("got %v POKT of income, want none", amount)
+			}
+		})
+	}
+}
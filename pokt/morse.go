@@ -0,0 +1,91 @@
+package pokt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const morseClaimableAccountPath = "%s/pokt-network/poktroll/migration/morse_claimable_account/%s"
+
+// MorseAccount is the state of a Morse account in the Shannon migration module.
+// Amounts are in POKT. Once claimed, the funds are held by ShannonDestAddress.
+type MorseAccount struct {
+	Address            string
+	UnstakedBalance    float64
+	SupplierStake      float64
+	ApplicationStake   float64
+	Claimed            bool
+	ShannonDestAddress string
+	ClaimedAtHeight    int64
+}
+
+type queryMorseClaimableAccountOutput struct {
+	MorseClaimableAccount struct {
+		ShannonDestAddress string  `json:"shannon_dest_address"`
+		MorseSrcAddress    string  `json:"morse_src_address"`
+		UnstakedBalance    Balance `json:"unstaked_balance"`
+		SupplierStake      Balance `json:"supplier_stake"`
+		ApplicationStake   Balance `json:"application_stake"`
+		ClaimedAtHeight    string  `json:"claimed_at_height"`
+	} `json:"morse_claimable_account"`
+}
+
+// GetMorseAccounts retrieves the claimable account state of each Morse address.
+// Addresses without a claimable account, which have no unclaimed balance, are skipped.
+func (c *Client) GetMorseAccounts(ctx context.Context, addresses []string) ([]MorseAccount, error) {
+	var accounts []MorseAccount
+
+	for _, address := range addresses {
+		url := fmt.Sprintf(morseClaimableAccountPath, c.Config.PathApiUrl, address)
+		resp, err := client.Get[queryMorseClaimableAccountOutput](ctx, url, c.header(), c.httpClient)
+		var httpErr *client.HTTPError
+		if errors.As(err, &httpErr) && httpErr.IsNotFound() {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Morse claimable account %s: %w", address, err)
+		}
+		claimable := resp.MorseClaimableAccount
+
+		account := MorseAccount{
+			Address:            address,
+			ShannonDestAddress: claimable.ShannonDestAddress,
+		}
+
+		if claimable.ClaimedAtHeight != "" {
+			account.ClaimedAtHeight, err = strconv.ParseInt(claimable.ClaimedAtHeight, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse claimed height: %s", claimable.ClaimedAtHeight)
+			}
+		}
+		account.Claimed = account.ClaimedAtHeight > 0
+
+		for _, amount := range []struct {
+			balance Balance
+			value   *float64
+		}{
+			{claimable.UnstakedBalance, &account.UnstakedBalance},
+			{claimable.SupplierStake, &account.SupplierStake},
+			{claimable.ApplicationStake, &account.ApplicationStake},
+		} {
+			if amount.balance.Amount == "" {
+				continue
+			}
+			upokt, err := parseAmount(amount.balance.Amount)
+			if err != nil {
+				return nil, err
+			}
+			*amount.value = upoktToPOKT(upokt)
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	c.progressChan <- "MORSE"
+
+	return accounts, nil
+}
@@ -0,0 +1,50 @@
+package pokt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetMorseAccountsSkipsAddressesWithoutClaimableAccount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/unclaimed"):
+			w.Write([]byte(`{"morse_claimable_account": {
+				"morse_src_address": "unclaimed",
+				"unstaked_balance": {"denom": "upokt", "amount": "2500000"},
+				"supplier_stake": {"denom": "upokt", "amount": "60000000000"},
+				"application_stake": {"denom": "upokt", "amount": "0"},
+				"claimed_at_height": "0"}}`))
+		case strings.HasSuffix(r.URL.Path, "/down"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": 5, "message": "morse claimable account not found", "details": []}`))
+		}
+	}))
+	defer server.Close()
+
+	newClient := func() *Client {
+		return NewClient(Config{PathApiUrl: server.URL, HttpClient: server.Client()}, make(chan string, 1), nil, nil)
+	}
+
+	accounts, err := newClient().GetMorseAccounts(context.Background(), []string{"missing", "unclaimed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("got %d accounts, want only the unclaimed account", len(accounts))
+	}
+	want := MorseAccount{Address: "unclaimed", UnstakedBalance: 2.5, SupplierStake: 60000}
+	if accounts[0] != want {
+		t.Errorf("account = %+v, want %+v", accounts[0], want)
+	}
+
+	// Other failures fail the run
+	if _, err := newClient().GetMorseAccounts(context.Background(), []string{"down", "unclaimed"}); err == nil {
+		t.Error("expected an error for a failed request")
+	}
+}
@@ -1,15 +1,21 @@
 package pokt
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math/big"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/commoddity/bank-informer/client"
 )
 
+// blockHeightHeader is the gRPC gateway header used to query state at a past height,
+// and returned with the height a query was served at.
+const blockHeightHeader = "x-cosmos-block-height"
+
 type Config struct {
 	PathApiUrl         string
 	PathApiKey         string
@@ -26,6 +32,7 @@ type Client struct {
 	progressChan chan string
 	mutex        *sync.Mutex
 	waitGroup    *sync.WaitGroup
+	blockHeight  int64
 }
 
 type Balance struct {
@@ -55,6 +62,11 @@ func NewClient(config Config, progressChan chan string, mutex *sync.Mutex, waitG
 	}
 }
 
+// BlockHeight returns the height the wallet balance was last fetched at.
+func (c *Client) BlockHeight() int64 {
+	return c.blockHeight
+}
+
 func ValidatePortalAppID(id string) error {
 	if len(id) != 8 && len(id) != 24 {
 		return fmt.Errorf("invalid Portal App ID: %s", id)
@@ -75,53 +87,16 @@ func ValidateSecretKey(key string) error {
 	return nil
 }
 
-func (c *Client) GetWalletBalance(balances map[string]float64) error {
-	var balance *big.Int
-	var highestBalance *big.Int
-	var successfulAttempts int
-
-	// Create a channel to receive balance results
-	balanceChan := make(chan *big.Int, 5)
-	errorChan := make(chan error, 5)
-
-	for i := 0; i < 5; i++ {
-		c.waitGroup.Add(1)
-		go func() {
-			defer c.waitGroup.Done()
-			var balance *big.Int
-			var err error
-			for attempt := 0; attempt < 5; attempt++ {
-				balance, err = c.getPOKTWalletBalance(c.Config.POKTWalletAddress)
-				if err == nil {
-					balanceChan <- balance
-					return
-				}
-			}
-			errorChan <- err
-		}()
-	}
-
-	// Wait for all goroutines to finish
-	c.waitGroup.Wait()
-	close(balanceChan)
-	close(errorChan)
-
-	// Process the balance results
-	for balance = range balanceChan {
-		successfulAttempts++
-		// If it's the first iteration or the current balance is higher than the highest, update the highest balance
-		if highestBalance == nil || balance.Cmp(highestBalance) > 0 {
-			highestBalance = balance
-		}
-	}
-
-	// If there were no successful attempts, return an error
-	if successfulAttempts == 0 {
-		return <-errorChan
+func (c *Client) GetWalletBalance(ctx context.Context, balances map[string]float64) error {
+	// Failed requests are retried by the HTTP client's retry policy
+	balance, height, err := c.getPOKTWalletBalance(ctx, c.Config.POKTWalletAddress, 0)
+	if err != nil {
+		return err
 	}
+	c.blockHeight = height
 
 	// Convert balance to float64 and divide by 1e6 to get the correct value
-	balanceFloat := new(big.Float).SetInt(highestBalance)
+	balanceFloat := new(big.Float).SetInt(balance)
 	balanceFloat.Quo(balanceFloat, big.NewFloat(1e6))
 	balanceValue, _ := balanceFloat.Float64()
 
@@ -141,18 +116,32 @@ func (c *Client) GetExchangeAmount() int64 {
 	return c.Config.PoktExchangeAmount
 }
 
-func (c *Client) getPOKTWalletBalance(address string) (*big.Int, error) {
+// GetWalletBalanceAtHeight returns the POKT balance of the configured wallet
+// address at the given height.
+func (c *Client) GetWalletBalanceAtHeight(ctx context.Context, height int64) (float64, error) {
+	balance, _, err := c.getPOKTWalletBalance(ctx, c.Config.POKTWalletAddress, height)
+	if err != nil {
+		return 0, err
+	}
+	return upoktToPOKT(new(big.Float).SetInt(balance)), nil
+}
+
+// getPOKTWalletBalance returns the upokt balance of the address at the given
+// height, or at the latest height if height is 0, and the height it was read at.
+// The height is 0 if the gateway did not return it.
+func (c *Client) getPOKTWalletBalance(ctx context.Context, address string, height int64) (*big.Int, int64, error) {
 	url := fmt.Sprintf("%s/%s", c.baseUrl, address)
 
-	header := http.Header{
-		"Target-Service-Id": []string{"pocket"},
-		"Authorization":     []string{c.pathAPIKey},
+	header := c.header()
+	if height > 0 {
+		header.Set(blockHeightHeader, strconv.FormatInt(height, 10))
 	}
 
-	resp, err := client.Get[queryBalanceOutput](url, header, c.httpClient)
+	resp, respHeader, err := client.GetWithHeader[queryBalanceOutput](ctx, url, header, c.httpClient)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
+	readHeight, _ := strconv.ParseInt(respHeader.Get(blockHeightHeader), 10, 64)
 
 	// Find the upokt balance in the balances array
 	for _, balance := range resp.Balances {
@@ -160,11 +149,11 @@ func (c *Client) getPOKTWalletBalance(address string) (*big.Int, error) {
 			amount := new(big.Int)
 			amount, ok := amount.SetString(balance.Amount, 10)
 			if !ok {
-				return nil, fmt.Errorf("failed to parse balance amount: %s", balance.Amount)
+				return nil, 0, fmt.Errorf("failed to parse balance amount: %s", balance.Amount)
 			}
-			return amount, nil
+			return amount, readHeight, nil
 		}
 	}
 
-	return nil, fmt.Errorf("upokt balance not found")
+	return nil, 0, fmt.Errorf("upokt balance not found")
 }
@@ -0,0 +1,126 @@
+package pokt
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const (
+	delegationsPath          = "%s/cosmos/staking/v1beta1/delegations/%s"
+	unbondingDelegationsPath = "%s/cosmos/staking/v1beta1/delegators/%s/unbonding_delegations"
+	delegatorRewardsPath     = "%s/cosmos/distribution/v1beta1/delegators/%s/rewards"
+)
+
+// StakingBalances holds the non-liquid POKT amounts of a wallet, in POKT.
+type StakingBalances struct {
+	Delegated float64
+	Unbonding float64
+	Rewards   float64
+}
+
+type queryDelegationsOutput struct {
+	DelegationResponses []struct {
+		Balance Balance `json:"balance"`
+	} `json:"delegation_responses"`
+}
+
+type queryUnbondingDelegationsOutput struct {
+	UnbondingResponses []struct {
+		Entries []struct {
+			Balance string `json:"balance"`
+		} `json:"entries"`
+	} `json:"unbonding_responses"`
+}
+
+type queryDelegatorRewardsOutput struct {
+	// Reward amounts are returned as decimal coins, e.g. "1234.560000000000000000"
+	Total []Balance `json:"total"`
+}
+
+// GetStakingBalances retrieves the delegated, unbonding and claimable reward
+// amounts for the configured POKT wallet address.
+func (c *Client) GetStakingBalances(ctx context.Context) (StakingBalances, error) {
+	var staking StakingBalances
+	address := c.Config.POKTWalletAddress
+
+	delegations, err := client.Get[queryDelegationsOutput](ctx, fmt.Sprintf(delegationsPath, c.Config.PathApiUrl, address), c.header(), c.httpClient)
+	if err != nil {
+		return staking, fmt.Errorf("failed to get delegations: %w", err)
+	}
+	delegated := new(big.Float)
+	for _, delegation := range delegations.DelegationResponses {
+		if delegation.Balance.Denom != "upokt" {
+			continue
+		}
+		amount, err := parseAmount(delegation.Balance.Amount)
+		if err != nil {
+			return staking, err
+		}
+		delegated.Add(delegated, amount)
+	}
+
+	unbondingDelegations, err := client.Get[queryUnbondingDelegationsOutput](ctx, fmt.Sprintf(unbondingDelegationsPath, c.Config.PathApiUrl, address), c.header(), c.httpClient)
+	if err != nil {
+		return staking, fmt.Errorf("failed to get unbonding delegations: %w", err)
+	}
+	unbonding := new(big.Float)
+	for _, response := range unbondingDelegations.UnbondingResponses {
+		for _, entry := range response.Entries {
+			amount, err := parseAmount(entry.Balance)
+			if err != nil {
+				return staking, err
+			}
+			unbonding.Add(unbonding, amount)
+		}
+	}
+
+	rewards, err := client.Get[queryDelegatorRewardsOutput](ctx, fmt.Sprintf(delegatorRewardsPath, c.Config.PathApiUrl, address), c.header(), c.httpClient)
+	if err != nil {
+		return staking, fmt.Errorf("failed to get delegator rewards: %w", err)
+	}
+	claimable := new(big.Float)
+	for _, reward := range rewards.Total {
+		if reward.Denom != "upokt" {
+			continue
+		}
+		amount, err := parseAmount(reward.Amount)
+		if err != nil {
+			return staking, err
+		}
+		claimable.Add(claimable, amount)
+	}
+
+	staking.Delegated = upoktToPOKT(delegated)
+	staking.Unbonding = upoktToPOKT(unbonding)
+	staking.Rewards = upoktToPOKT(claimable)
+
+	c.progressChan <- "STAKE"
+
+	return staking, nil
+}
+
+func (c *Client) header() http.Header {
+	return http.Header{
+		"Target-Service-Id": []string{"pocket"},
+		"Authorization":     []string{c.pathAPIKey},
+	}
+}
+
+// parseAmount parses an integer or decimal upokt amount string.
+func parseAmount(amount string) (*big.Float, error) {
+	value, ok := new(big.Float).SetString(amount)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse amount: %s", amount)
+	}
+	return value, nil
+}
+
+func upoktToPOKT(upokt *big.Float) float64 {
+	pokt := new(big.Float).Quo(upokt, big.NewFloat(1e6))
+	value, _ := pokt.Float64()
+	return value
+}
@@ -0,0 +1,233 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuoteRecorder stores the quotes returned by each provider for later review.
+type QuoteRecorder interface {
+	WriteProviderQuotes(provider, fiat string, quotes map[string]float64, t time.Time) error
+}
+
+// Divergence is a symbol whose provider quotes differ by more than the threshold.
+type Divergence struct {
+	Symbol    string
+	Fiat      string
+	Spread    float64 // (highest - lowest) / median, as a percentage
+	Median    float64
+	Quotes    map[string]float64
+	Threshold float64
+}
+
+// Consensus is a Provider that queries multiple providers concurrently and
+// uses the median of their quotes for each symbol.
+type Consensus struct {
+	providers   []Provider
+	threshold   float64
+	recorder    QuoteRecorder
+	mutex       sync.Mutex
+	divergences []Divergence
+	failures    []error
+	// overBudget are the providers left out of the run because they are over their credit budget
+	overBudget map[string]error
+}
+
+// NewConsensus creates a consensus provider. Symbols whose quotes spread by more than
+// threshold percent of the median are reported by Divergences. If recorder is not nil,
+// the quotes of each provider are stored with it.
+func NewConsensus(providers []Provider, threshold float64, recorder QuoteRecorder) *Consensus {
+	return &Consensus{
+		providers: providers,
+		threshold: threshold,
+		recorder:  recorder,
+	}
+}
+
+func (c *Consensus) Name() string {
+	names := make([]string, len(c.providers))
+	for i, provider := range c.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// GetQuotes returns the median quote of each symbol across all providers that returned
+// a quote for it. It only returns an error if every provider failed.
+func (c *Consensus) GetQuotes(ctx context.Context, symbols []string, fiat string) (map[string]float64, error) {
+	providerQuotes := make(map[string]map[string]float64)
+	var errs []error
+	var mutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	now := time.Now()
+
+	for _, provider := range c.runProviders() {
+		waitGroup.Add(1)
+		go func(provider Provider) {
+			defer waitGroup.Done()
+
+			quotes, err := provider.GetQuotes(ctx, symbols, fiat)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s quotes: %w", provider.Name(), fiat, err))
+				return
+			}
+			providerQuotes[provider.Name()] = quotes
+		}(provider)
+	}
+	waitGroup.Wait()
+
+	// Join the errors, so that a circuit breaker error is found whichever provider failed first
+	if len(providerQuotes) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if c.recorder != nil {
+		for name, quotes := range providerQuotes {
+			if err := c.recorder.WriteProviderQuotes(name, fiat, quotes, now); err != nil {
+				errs = append(errs, fmt.Errorf("failed to store %s %s quotes: %w", name, fiat, err))
+			}
+		}
+	}
+
+	prices := make(map[string]float64)
+	var divergences []Divergence
+	for _, symbol := range symbols {
+		quotes := make(map[string]float64)
+		var values []float64
+		for name, providerQuote := range providerQuotes {
+			if quote, ok := providerQuote[symbol]; ok {
+				quotes[name] = quote
+				values = append(values, quote)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		sort.Float64s(values)
+		median := median(values)
+		prices[symbol] = median
+
+		if median == 0 {
+			continue
+		}
+		spread := (values[len(values)-1] - values[0]) / median * 100
+		if spread > c.threshold {
+			divergences = append(divergences, Divergence{
+				Symbol:    symbol,
+				Fiat:      fiat,
+				Spread:    spread,
+				Median:    median,
+				Quotes:    quotes,
+				Threshold: c.threshold,
+			})
+		}
+	}
+
+	c.mutex.Lock()
+	c.divergences = append(c.divergences, divergences...)
+	c.failures = append(c.failures, errs...)
+	c.mutex.Unlock()
+
+	return prices, nil
+}
+
+// CheckBudget leaves the providers that would exceed their credit budget out of
+// the run, which are returned by OverBudget. It only returns an error if every
+// provider is over budget.
+func (c *Consensus) CheckBudget(symbols []string, fiats []string, extraCredits map[string]int) error {
+	overBudget := make(map[string]error)
+	var errs []error
+	for _, provider := range c.providers {
+		if budget, ok := provider.(CreditBudget); ok {
+			if err := budget.CheckBudget(symbols, fiats, extraCredits); err != nil {
+				overBudget[provider.Name()] = err
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(overBudget) == len(c.providers) {
+		c.overBudget = nil
+		return errors.Join(errs...)
+	}
+	c.overBudget = overBudget
+	return nil
+}
+
+// OverBudget returns the errors of the providers left out of the run by CheckBudget.
+func (c *Consensus) OverBudget() []error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	errs := make([]error, 0, len(c.overBudget))
+	for _, provider := range c.providers {
+		if err, ok := c.overBudget[provider.Name()]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// runProviders returns the providers that are not over their credit budget.
+func (c *Consensus) runProviders() []Provider {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var providers []Provider
+	for _, provider := range c.providers {
+		if _, ok := c.overBudget[provider.Name()]; !ok {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// Divergences returns the symbols whose provider quotes diverged in any fiat currency.
+func (c *Consensus) Divergences() []Divergence {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.divergences
+}
+
+// Failures returns the errors of providers that failed while others succeeded.
+func (c *Consensus) Failures() []error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.failures
+}
+
+func (d Divergence) String() string {
+	names := make([]string, 0, len(d.Quotes))
+	for name := range d.Quotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	quotes := make([]string, len(names))
+	for i, name := range names {
+		quotes[i] = fmt.Sprintf("%s %g", name, d.Quotes[name])
+	}
+
+	return fmt.Sprintf("%s price quotes diverge by %.2f%% (over %.2f%%): %s %s",
+		d.Symbol, d.Spread, d.Threshold, strings.Join(quotes, ", "), d.Fiat)
+}
+
+// median returns the median of sorted values.
+func median(values []float64) float64 {
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
@@ -0,0 +1,422 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/commoddity/bank-informer/client"
+	"github.com/commoddity/bank-informer/fx"
+	"github.com/commoddity/bank-informer/persistence"
+)
+
+// Provider fetches the prices of crypto symbols in a fiat currency.
+type Provider interface {
+	// Name returns the name of the price provider, e.g. "cmc".
+	Name() string
+	// GetQuotes returns the price in fiat of each symbol the provider has a price for.
+	GetQuotes(ctx context.Context, symbols []string, fiat string) (map[string]float64, error)
+}
+
+// RateCache stores the last fetched exchange rates.
+type RateCache interface {
+	WriteExchangeRates(rates persistence.CachedExchangeRates) error
+	GetExchangeRates() (persistence.CachedExchangeRates, error)
+}
+
+// FXStore stores the last fetched FX rates.
+type FXStore interface {
+	WriteFXRates(rates persistence.FXRates) error
+	GetFXRates() (persistence.FXRates, error)
+}
+
+// HistoricalProvider fetches past prices of crypto symbols in a fiat currency.
+type HistoricalProvider interface {
+	// GetHistoricalQuotes returns the closing price on day in fiat of each symbol
+	// the provider has a price for.
+	GetHistoricalQuotes(ctx context.Context, symbols []string, fiat string, day time.Time) (map[string]float64, error)
+	// GetHistoricalPricesAt returns the price of symbol in fiat nearest to each of
+	// the times, or 0 for times the provider has no price near.
+	GetHistoricalPricesAt(ctx context.Context, symbol, fiat string, times []time.Time) ([]float64, error)
+}
+
+// CreditBudget is implemented by providers with a limited number of API credits.
+type CreditBudget interface {
+	// CheckBudget returns an error if fetching the symbols in each of the fiat currencies
+	// would exceed the provider's credit budget. extraCredits are the credits other calls
+	// of the run will use, by the name of the provider whose API they are used from.
+	CheckBudget(symbols []string, fiats []string, extraCredits map[string]int) error
+}
+
+// CreditEstimator is implemented by FX providers that use API credits.
+type CreditEstimator interface {
+	// EstimateCredits returns the credits used by GetRates for base and quotes.
+	EstimateCredits(base string, quotes []string) int
+}
+
+type Config struct {
+	Provider          Provider
+	ConvertCurrencies []string
+	// FX is optional. If set, crypto prices are only fetched in BaseCurrency
+	// and converted to the other convert currencies using FX rates.
+	FX           fx.Provider
+	FXStore      FXStore
+	BaseCurrency string
+	// Cache is optional. Cached rates younger than CacheMaxAge are used instead
+	// of fetching new rates, and in offline mode cached rates of any age are used.
+	Cache       RateCache
+	CacheMaxAge time.Duration
+	Offline     bool
+	// Aliases maps symbols to the symbol whose price they are valued at,
+	// e.g. wrapped or bridged tokens to the canonical token.
+	Aliases map[string]string
+}
+
+type Client struct {
+	Config            Config
+	provider          Provider
+	convertCurrencies []string
+	cachedAt          time.Time
+	budgetErr         error
+	circuitErr        error
+	progressChan      chan string
+	mutex             *sync.Mutex
+	waitGroup         *sync.WaitGroup
+}
+
+func NewClient(config Config, progressChan chan string, mutex *sync.Mutex, waitGroup *sync.WaitGroup) *Client {
+	return &Client{
+		Config:            config,
+		provider:          config.Provider,
+		convertCurrencies: config.ConvertCurrencies,
+		progressChan:      progressChan,
+		mutex:             mutex,
+		waitGroup:         waitGroup,
+	}
+}
+
+// GetAllExchangeRates returns the exchange rates of each balance symbol in each convert
+// currency, from the cache if allowed by the cache settings or else from the provider.
+// If fetching from the provider would exceed its credit budget, cached rates of any age
+// are used instead, and the reason is returned by BudgetExceeded. The same happens if
+// the circuit breaker of a price upstream is open, with the reason returned by CircuitOpen.
+func (c *Client) GetAllExchangeRates(ctx context.Context, balances map[string]float64) (map[string]map[string]float64, error) {
+	symbols := getCurrencyKeys(balances)
+
+	if cached, ok := c.getCachedExchangeRates(symbols, c.Config.Offline); ok {
+		return c.useCachedExchangeRates(cached), nil
+	}
+	if c.Config.Offline {
+		return nil, fmt.Errorf("no cached exchange rates available for offline mode")
+	}
+
+	if budget, ok := c.provider.(CreditBudget); ok {
+		if err := budget.CheckBudget(symbols, c.fetchCurrencies(), c.fxCredits()); err != nil {
+			cached, ok := c.getCachedExchangeRates(symbols, true)
+			if !ok {
+				return nil, fmt.Errorf("%w, and no cached exchange rates are available", err)
+			}
+			c.budgetErr = err
+			return c.useCachedExchangeRates(cached), nil
+		}
+	}
+
+	exchangeRates, err := c.fetchExchangeRates(ctx, symbols)
+	if errors.Is(err, client.ErrCircuitOpen) {
+		cached, ok := c.getCachedExchangeRates(symbols, true)
+		if !ok {
+			return nil, fmt.Errorf("%w, and no cached exchange rates are available", err)
+		}
+		c.circuitErr = err
+		return c.useCachedExchangeRates(cached), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Config.Cache != nil {
+		err = c.Config.Cache.WriteExchangeRates(persistence.CachedExchangeRates{
+			Time:          time.Now(),
+			Symbols:       symbols,
+			ExchangeRates: exchangeRates,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to cache exchange rates: %w", err)
+		}
+	}
+
+	return exchangeRates, nil
+}
+
+// CachedAt returns the time the exchange rates were fetched at, if the last
+// call to GetAllExchangeRates used cached rates.
+func (c *Client) CachedAt() (time.Time, bool) {
+	return c.cachedAt, !c.cachedAt.IsZero()
+}
+
+// BudgetExceeded returns the reason cached exchange rates were used instead of
+// fetching new ones, if the last call to GetAllExchangeRates was over budget.
+func (c *Client) BudgetExceeded() error {
+	return c.budgetErr
+}
+
+// CircuitOpen returns the reason cached exchange rates were used instead of fetching
+// new ones, if the last call to GetAllExchangeRates found a price upstream down.
+func (c *Client) CircuitOpen() error {
+	return c.circuitErr
+}
+
+func (c *Client) useCachedExchangeRates(cached persistence.CachedExchangeRates) map[string]map[string]float64 {
+	c.cachedAt = cached.Time
+	for _, currency := range c.convertCurrencies {
+		c.progressChan <- currency
+	}
+	return cached.ExchangeRates
+}
+
+// getCachedExchangeRates returns the cached exchange rates if they may be used
+// and were fetched for all of the symbols and convert currencies. If anyAge is
+// set, they may be used regardless of the cache max age.
+func (c *Client) getCachedExchangeRates(symbols []string, anyAge bool) (persistence.CachedExchangeRates, bool) {
+	if c.Config.Cache == nil || (!anyAge && c.Config.CacheMaxAge <= 0) {
+		return persistence.CachedExchangeRates{}, false
+	}
+
+	cached, err := c.Config.Cache.GetExchangeRates()
+	if err != nil {
+		return cached, false
+	}
+	if !anyAge && time.Since(cached.Time) > c.Config.CacheMaxAge {
+		return cached, false
+	}
+
+	for _, symbol := range symbols {
+		if !slices.Contains(cached.Symbols, symbol) {
+			return cached, false
+		}
+	}
+	for _, currency := range c.convertCurrencies {
+		if _, ok := cached.ExchangeRates[currency]; !ok {
+			return cached, false
+		}
+	}
+
+	return cached, true
+}
+
+// fetchCurrencies returns the currencies crypto prices are fetched from the provider in.
+func (c *Client) fetchCurrencies() []string {
+	if c.Config.FX != nil {
+		return []string{c.Config.BaseCurrency}
+	}
+	return c.convertCurrencies
+}
+
+func (c *Client) fetchExchangeRates(ctx context.Context, symbols []string) (map[string]map[string]float64, error) {
+	if c.Config.FX != nil {
+		return c.fetchExchangeRatesWithFX(ctx, symbols)
+	}
+	return c.fetchExchangeRatesPerCurrency(ctx, symbols)
+}
+
+// fetchExchangeRatesWithFX fetches crypto prices once in the base currency,
+// then converts them to each convert currency using FX rates.
+func (c *Client) fetchExchangeRatesWithFX(ctx context.Context, symbols []string) (map[string]map[string]float64, error) {
+	base := c.Config.BaseCurrency
+
+	basePrices, err := c.getQuotes(ctx, symbols, base)
+	if err != nil {
+		return nil, err
+	}
+
+	fxRates, err := c.getFXRates(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeRates := make(map[string]map[string]float64)
+	for _, currency := range c.convertCurrencies {
+		fxRate, ok := fxRates[currency]
+		if !ok {
+			return nil, fmt.Errorf("no FX rate found for %s/%s", base, currency)
+		}
+
+		exchangeRates[currency] = make(map[string]float64)
+		for symbol, price := range basePrices {
+			exchangeRates[currency][symbol] = price * fxRate
+		}
+
+		c.progressChan <- currency
+	}
+
+	return exchangeRates, nil
+}
+
+// getFXRates returns the FX rates from base to each convert currency. FX rates are
+// published daily, so the stored rates are reused if they were fetched today.
+func (c *Client) getFXRates(ctx context.Context, base string) (map[string]float64, error) {
+	// Rates from a local file are not stored, so that changes to the file are picked up
+	if _, ok := c.Config.FX.(*fx.File); ok || c.Config.FXStore == nil {
+		return c.Config.FX.GetRates(ctx, base, c.convertCurrencies)
+	}
+
+	if stored, ok := c.getStoredFXRates(base); ok {
+		return stored, nil
+	}
+
+	fxRates, err := c.Config.FX.GetRates(ctx, base, c.convertCurrencies)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Config.FXStore.WriteFXRates(persistence.FXRates{
+		Provider: c.Config.FX.Name(),
+		Time:     time.Now(),
+		Base:     base,
+		Rates:    fxRates,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store FX rates: %w", err)
+	}
+
+	return fxRates, nil
+}
+
+// getStoredFXRates returns the stored FX rates if they are from the FX provider,
+// were fetched today and have a rate for each convert currency.
+func (c *Client) getStoredFXRates(base string) (map[string]float64, bool) {
+	stored, err := c.Config.FXStore.GetFXRates()
+	if err != nil {
+		return nil, false
+	}
+	if stored.Provider != c.Config.FX.Name() || stored.Base != base ||
+		!sameDay(stored.Time, time.Now()) || !hasRates(stored.Rates, c.convertCurrencies) {
+		return nil, false
+	}
+	return stored.Rates, true
+}
+
+// fxCredits returns the API credits the FX provider will use during the run,
+// by the name of the FX provider.
+func (c *Client) fxCredits() map[string]int {
+	estimator, ok := c.Config.FX.(CreditEstimator)
+	if !ok {
+		return nil
+	}
+	if c.Config.FXStore != nil {
+		if _, ok := c.getStoredFXRates(c.Config.BaseCurrency); ok {
+			return nil
+		}
+	}
+	return map[string]int{c.Config.FX.Name(): estimator.EstimateCredits(c.Config.BaseCurrency, c.convertCurrencies)}
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Local().Format(time.DateOnly) == b.Local().Format(time.DateOnly)
+}
+
+func hasRates(rates map[string]float64, currencies []string) bool {
+	for _, currency := range currencies {
+		if _, ok := rates[currency]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchExchangeRatesPerCurrency fetches crypto prices in each convert currency.
+func (c *Client) fetchExchangeRatesPerCurrency(ctx context.Context, symbols []string) (map[string]map[string]float64, error) {
+	exchangeRates := make(map[string]map[string]float64)
+	errorChan := make(chan error, len(c.convertCurrencies))
+
+	// For each currency in the list of currencies to convert
+	for _, convertCurrency := range c.convertCurrencies {
+		c.waitGroup.Add(1)
+		go func(currency string) {
+			defer c.waitGroup.Done()
+
+			// Retrieve and store the exchange rates for the current currency
+			currencyExchangeRates, err := c.getQuotes(ctx, symbols, currency)
+			if err != nil {
+				errorChan <- err
+				return
+			}
+
+			c.mutex.Lock()
+			// Add the retrieved exchange rates to the map of exchange rates
+			exchangeRates[currency] = currencyExchangeRates
+			c.mutex.Unlock()
+
+			c.progressChan <- currency
+		}(convertCurrency)
+	}
+
+	c.waitGroup.Wait()
+	close(errorChan)
+
+	// Check if there were any errors
+	if len(errorChan) > 0 {
+		return nil, <-errorChan
+	}
+
+	return exchangeRates, nil
+}
+
+// getQuotes fetches the price of each symbol from the provider. Aliased symbols
+// are fetched as their canonical symbol and given the canonical symbol's price.
+func (c *Client) getQuotes(ctx context.Context, symbols []string, fiat string) (map[string]float64, error) {
+	var requestSymbols []string
+	for _, symbol := range symbols {
+		if canonical, ok := c.Config.Aliases[symbol]; ok {
+			symbol = canonical
+		}
+		if !slices.Contains(requestSymbols, symbol) {
+			requestSymbols = append(requestSymbols, symbol)
+		}
+	}
+
+	quotes, err := c.provider.GetQuotes(ctx, requestSymbols, fiat)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, symbol := range symbols {
+		if canonical, ok := c.Config.Aliases[symbol]; ok {
+			if quote, ok := quotes[canonical]; ok {
+				quotes[symbol] = quote
+			}
+		}
+	}
+
+	return quotes, nil
+}
+
+func (c *Client) GetFiatValues(balances map[string]float64, fiatExchangeRates map[string]map[string]float64) map[string]float64 {
+	fiatValues := make(map[string]float64)
+
+	for fiat, exchangeRates := range fiatExchangeRates {
+		for currency, balance := range balances {
+			if exchangeRate, ok := exchangeRates[currency]; ok {
+				fiatValue := balance * exchangeRate
+				roundedFiatValue := math.Round(fiatValue*100) / 100
+				fiatValues[fiat] += roundedFiatValue
+			}
+		}
+	}
+
+	return fiatValues
+}
+
+func getCurrencyKeys(balances map[string]float64) []string {
+	currencyKeys := make([]string, 0, len(balances))
+	for key := range balances {
+		currencyKeys = append(currencyKeys, key)
+	}
+	sort.Strings(currencyKeys)
+	return currencyKeys
+}
@@ -0,0 +1,69 @@
+package price
+
+import (
+	"net/http"
+
+	"github.com/commoddity/bank-informer/cmc"
+	"github.com/commoddity/bank-informer/coingecko"
+	"github.com/commoddity/bank-informer/config"
+	"github.com/commoddity/bank-informer/eth"
+	"github.com/commoddity/bank-informer/fx"
+	"github.com/commoddity/bank-informer/onchain"
+)
+
+// NewProvider creates the named price provider with the settings of the config.
+func NewProvider(name string, cfg *config.Config, credits cmc.CreditStore, httpClient *http.Client) Provider {
+	switch name {
+	case config.PriceProviderOnchain:
+		pools := make(map[string]onchain.Pool)
+		for symbol, pool := range cfg.OnchainPools {
+			pools[symbol] = onchain.Pool{
+				Address: pool.Address,
+				Version: pool.Version,
+				Token:   pool.Token,
+				Quote:   pool.Quote,
+			}
+		}
+		return onchain.NewClient(onchain.Config{
+			// Contract calls are sent through the PATH eth service
+			Caller: eth.NewClient(eth.Config{
+				PathApiUrl: cfg.PathApiUrl,
+				PathApiKey: cfg.PathApiKey,
+				HttpClient: httpClient,
+			}, nil, nil, nil),
+			Feeds:       cfg.OnchainFeeds,
+			Pools:       pools,
+			MaxQuoteAge: cfg.OnchainMaxQuoteAge,
+		})
+	case config.PriceProviderCoinGecko:
+		return coingecko.NewClient(coingecko.Config{
+			APIKey:     cfg.CoinGeckoAPIKey,
+			Pro:        cfg.CoinGeckoPro,
+			IDs:        cfg.CoinGeckoIDs,
+			HttpClient: httpClient,
+		})
+	default:
+		return cmc.NewClient(cmc.Config{
+			CMCAPIKey:           cfg.CMCAPIKey,
+			IDs:                 cfg.CMCIDs,
+			Credits:             credits,
+			MonthlyCreditBudget: cfg.CMCMonthlyCreditBudget,
+			HttpClient:          httpClient,
+		})
+	}
+}
+
+// NewFXProvider creates the FX provider selected in the config, or returns
+// nil if crypto prices should be fetched in each convert currency instead.
+func NewFXProvider(cfg *config.Config, credits cmc.CreditStore, httpClient *http.Client) fx.Provider {
+	switch cfg.FXProvider {
+	case config.FXProviderECB:
+		return fx.NewECB(httpClient)
+	case config.FXProviderCMC:
+		return fx.NewCMC(cfg.CMCAPIKey, credits, httpClient)
+	case config.FXProviderFile:
+		return fx.NewFile(cfg.FXFile)
+	default:
+		return nil
+	}
+}
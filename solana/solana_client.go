@@ -0,0 +1,210 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/commoddity/bank-informer/client"
+)
+
+const (
+	tokenProgramID     = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	token2022ProgramID = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+
+	lamportsPerSOL = 1e9
+)
+
+// defaultTokenMints maps well-known SPL token mints to their symbols.
+var defaultTokenMints = map[string]string{
+	"EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v": "USDC",
+	"Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB": "USDT",
+}
+
+type Config struct {
+	RpcUrl          string
+	PathApiUrl      string
+	PathApiKey      string
+	ServiceID       string
+	WalletAddresses []string
+	TokenMints      map[string]string
+	HttpClient      *http.Client
+}
+
+type Client struct {
+	Config       Config
+	url          string
+	header       http.Header
+	tokenMints   map[string]string
+	httpClient   *http.Client
+	progressChan chan string
+	mutex        *sync.Mutex
+	waitGroup    *sync.WaitGroup
+	slot         int64
+}
+
+type (
+	jsonRPCRequest struct {
+		Jsonrpc string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  []any  `json:"params"`
+		Id      int    `json:"id"`
+	}
+
+	jsonRPCResponse[T any] struct {
+		Result struct {
+			Context struct {
+				Slot int64 `json:"slot"`
+			} `json:"context"`
+			Value T `json:"value"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error,omitempty"`
+	}
+
+	tokenAccount struct {
+		Pubkey  string `json:"pubkey"`
+		Account struct {
+			Data struct {
+				Parsed struct {
+					Info struct {
+						Mint        string `json:"mint"`
+						TokenAmount struct {
+							Amount   string `json:"amount"`
+							Decimals int    `json:"decimals"`
+						} `json:"tokenAmount"`
+					} `json:"info"`
+				} `json:"parsed"`
+			} `json:"data"`
+		} `json:"account"`
+	}
+)
+
+// NewClient creates a Solana client. If a PATH service ID is configured, requests
+// are sent through PATH, otherwise they are sent directly to the RPC URL.
+func NewClient(config Config, progressChan chan string, mutex *sync.Mutex, waitGroup *sync.WaitGroup) *Client {
+	url := config.RpcUrl
+	header := http.Header{
+		"Content-Type": []string{"application/json"},
+	}
+	if config.ServiceID != "" {
+		url = config.PathApiUrl
+		header.Set("Target-Service-Id", config.ServiceID)
+		header.Set("Authorization", config.PathApiKey)
+	}
+
+	tokenMints := make(map[string]string)
+	for mint, symbol := range defaultTokenMints {
+		tokenMints[mint] = symbol
+	}
+	for mint, symbol := range config.TokenMints {
+		tokenMints[mint] = symbol
+	}
+
+	return &Client{
+		Config:       config,
+		url:          url,
+		header:       header,
+		tokenMints:   tokenMints,
+		httpClient:   config.HttpClient,
+		progressChan: progressChan,
+		mutex:        mutex,
+		waitGroup:    waitGroup,
+	}
+}
+
+// GetWalletBalances adds the SOL and SPL token balances of each configured
+// wallet address to the balances of the tracked symbols.
+func (c *Client) GetWalletBalances(ctx context.Context, balances map[string]float64) error {
+	solanaBalances := make(map[string]*big.Float)
+
+	for _, address := range c.Config.WalletAddresses {
+		lamports, err := rpc[uint64](ctx, c, "getBalance", address, map[string]string{"commitment": "confirmed"})
+		if err != nil {
+			return fmt.Errorf("failed to get SOL balance for %s: %w", address, err)
+		}
+		addBalance(solanaBalances, "SOL", new(big.Float).Quo(new(big.Float).SetUint64(lamports), big.NewFloat(lamportsPerSOL)))
+
+		for _, programID := range []string{tokenProgramID, token2022ProgramID} {
+			accounts, err := rpc[[]tokenAccount](ctx, c, "getTokenAccountsByOwner", address,
+				map[string]string{"programId": programID},
+				map[string]string{"encoding": "jsonParsed", "commitment": "confirmed"},
+			)
+			if err != nil {
+				return fmt.Errorf("failed to get token accounts for %s: %w", address, err)
+			}
+
+			for _, account := range accounts {
+				info := account.Account.Data.Parsed.Info
+				symbol, ok := c.tokenMints[info.Mint]
+				if !ok {
+					continue
+				}
+
+				amount, ok := new(big.Float).SetString(info.TokenAmount.Amount)
+				if !ok {
+					return fmt.Errorf("failed to parse token amount for %s: %s", account.Pubkey, info.TokenAmount.Amount)
+				}
+				decimals := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(info.TokenAmount.Decimals)), nil))
+				addBalance(solanaBalances, symbol, amount.Quo(amount, decimals))
+			}
+		}
+	}
+
+	c.progressChan <- "SOL"
+
+	// Add the balances of tracked symbols to the passed map
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for symbol, balance := range solanaBalances {
+		if _, ok := balances[symbol]; !ok {
+			continue
+		}
+		value, _ := balance.Float64()
+		balances[symbol] += value
+	}
+
+	return nil
+}
+
+// Slot returns the latest slot the wallet balances were fetched at.
+func (c *Client) Slot() int64 {
+	return c.slot
+}
+
+func addBalance(balances map[string]*big.Float, symbol string, amount *big.Float) {
+	if _, ok := balances[symbol]; !ok {
+		balances[symbol] = new(big.Float)
+	}
+	balances[symbol].Add(balances[symbol], amount)
+}
+
+// rpc sends a Solana JSON-RPC request and returns the value of its result.
+func rpc[T any](ctx context.Context, c *Client, method string, params ...any) (T, error) {
+	var value T
+
+	reqBody, err := json.Marshal(jsonRPCRequest{Jsonrpc: "2.0", Method: method, Params: params, Id: 1})
+	if err != nil {
+		return value, fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	// Solana queries are read-only, so they are safe to retry
+	header := c.header.Clone()
+	client.MarkIdempotent(header)
+
+	resp, err := client.Post[jsonRPCResponse[T]](ctx, c.url, header, reqBody, c.httpClient)
+	if err != nil {
+		return value, err
+	}
+	if resp.Error != nil {
+		return value, fmt.Errorf("error for method %s: %s", method, resp.Error.Message)
+	}
+
+	c.slot = max(c.slot, resp.Result.Context.Slot)
+	return resp.Result.Value, nil
+}